@@ -1,6 +1,9 @@
 package stringreader
 
-import "reflect"
+import (
+	"context"
+	"reflect"
+)
 
 // UnmarshalContext holds contextual data that is passed to parsers.
 // It contains an internal reference to a ParsingData object.
@@ -15,6 +18,31 @@ type UnmarshalContext interface {
 
 	// GetGlobal returns a global datum from the underlying ParsingData object.
 	GetGlobal(key string) interface{}
+
+	// Defaulted indicates if the value passed to the parser is a default value.
+	// See Marshal.DefaultTag.
+	Defaulted() bool
+
+	// Type returns the type of the destination field that is being written to.
+	// Parsers may use it to produce a value of exactly this type, instead of relying on conversion.
+	Type() reflect.Type
+
+	// Field returns the destination field that is being written to.
+	Field() reflect.StructField
+
+	// Stage returns the name of the current stage of a parser pipeline, e.g. "lower" for "trim|lower".
+	// For parsers that are not a pipeline, returns the same as Parser.
+	Stage() string
+
+	// Args returns the arguments passed to the current parser in the parser tag, e.g. {"base": "16"} for "int(base=16)".
+	// When there are no arguments, returns nil.
+	// The returned map must not be modified.
+	Args() map[string]string
+
+	// Context returns the context passed to Marshal.UnmarshalContextual.
+	// Parsers performing slow operations should stop when it is done.
+	// When no context was passed, returns context.Background().
+	Context() context.Context
 }
 
 // UnmarshalState holds the current state of the unmarshaling process.
@@ -28,6 +56,7 @@ type UnmarshalState interface {
 	Source() string
 
 	// Parser returns the name of the parser being used
+	// For a parser pipeline, returns the names of all stages separated by "|".
 	// When no parser is being used, returns the empty string.
 	Parser() string
 	// Single indicates if the parser being used is a SingleParser (true) or MultiParser (false).
@@ -93,16 +122,23 @@ func (p *ParsingData) DeleteLocal(field, key string) {
 // unmarshalContext is the implementation of UnmarshalContext.
 type unmarshalContext struct {
 	dest, source, parser string
-	single               bool
+	single, defaulted    bool
 	data                 ParsingData
 	tag                  reflect.StructTag
+	field                reflect.StructField
+	stage                string
+	args                 map[string]string
+	ctx                  context.Context
 }
 
 // Reset resets this parsing context to prepare it for re-use inside of a sync.Pool
 func (p *unmarshalContext) Reset() {
 	p.dest, p.source, p.parser = "", "", ""
-	p.single = false
+	p.single, p.defaulted = false, false
 	p.data = ParsingData{}
+	p.field = reflect.StructField{}
+	p.stage, p.args = "", nil
+	p.ctx = nil
 }
 
 // The remainder of functions implement UnmarshalContext.
@@ -124,6 +160,10 @@ func (p unmarshalContext) Single() bool {
 	return p.single
 }
 
+func (p unmarshalContext) Defaulted() bool {
+	return p.defaulted
+}
+
 func (p unmarshalContext) GetGlobal(key string) interface{} {
 	return p.data.Globals[key]
 }
@@ -135,3 +175,26 @@ func (p unmarshalContext) Get(key string) interface{} {
 func (p unmarshalContext) Tag() reflect.StructTag {
 	return p.tag
 }
+
+func (p unmarshalContext) Type() reflect.Type {
+	return p.field.Type
+}
+
+func (p unmarshalContext) Field() reflect.StructField {
+	return p.field
+}
+
+func (p unmarshalContext) Stage() string {
+	return p.stage
+}
+
+func (p unmarshalContext) Args() map[string]string {
+	return p.args
+}
+
+func (p unmarshalContext) Context() context.Context {
+	if p.ctx == nil {
+		return context.Background()
+	}
+	return p.ctx
+}
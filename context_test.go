@@ -1,6 +1,10 @@
 package stringreader
 
-import "fmt"
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
 
 func ExampleParsingData() {
 	var data ParsingData
@@ -17,3 +21,46 @@ func ExampleParsingData() {
 	// data.Globals["world"] = 42
 	// data.Locals["field"]["world"] = 7
 }
+
+// A single generic parser can produce exactly the type of the destination field.
+func ExampleUnmarshalContext_Type() {
+	var m Marshal
+	m.ParserTag = "type"
+	m.RegisterSingleParser("number", func(value string, ok bool, ctx UnmarshalContext) (interface{}, error) {
+		result := reflect.New(ctx.Type()).Elem()
+		switch result.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			i, err := strconv.ParseInt(value, 10, ctx.Type().Bits())
+			if err != nil {
+				return nil, err
+			}
+			result.SetInt(i)
+		case reflect.Float32, reflect.Float64:
+			f, err := strconv.ParseFloat(value, ctx.Type().Bits())
+			if err != nil {
+				return nil, err
+			}
+			result.SetFloat(f)
+		default:
+			return nil, fmt.Errorf("field %s is not a number", ctx.Field().Name)
+		}
+		return result.Interface(), nil
+	})
+
+	var dest struct {
+		Small int8    `type:"number"`
+		Large int64   `type:"number"`
+		Ratio float32 `type:"number"`
+	}
+
+	// values that do not fit into the destination are rejected, instead of being truncated.
+	err := m.UnmarshalSingle(&dest, SourceSingleMap{"Small": "300"})
+	fmt.Println(err)
+
+	err = m.UnmarshalSingle(&dest, SourceSingleMap{"Small": "-12", "Large": "1234567890123", "Ratio": "0.5"})
+	fmt.Println(err, dest)
+
+	// Output:
+	// Marshal.Unmarshal: Failed to parse field "Small": strconv.ParseInt: parsing "300": value out of range
+	// <nil> {-12 1234567890123 0.5}
+}
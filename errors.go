@@ -3,6 +3,7 @@ package stringreader
 import (
 	"fmt"
 	"reflect"
+	"strings"
 
 	"errors"
 )
@@ -19,6 +20,15 @@ var _ UnmarshalError = (*ErrInlineNotStruct)(nil)
 var _ UnmarshalError = (*ErrUnknownParser)(nil)
 var _ UnmarshalError = (*ErrFailedToParseField)(nil)
 var _ UnmarshalError = (*ErrWrongDestType)(nil)
+var _ UnmarshalError = (*ErrUnknownFormatter)(nil)
+var _ UnmarshalError = (*ErrFailedToFormatField)(nil)
+var _ UnmarshalError = (*ErrCollected)(nil)
+var _ UnmarshalError = (*ErrUnknownSource)(nil)
+var _ UnmarshalError = (*ErrInvalidRequest)(nil)
+var _ UnmarshalError = (*ErrMissingRequired)(nil)
+var _ UnmarshalError = (*ErrValidationFailed)(nil)
+var _ UnmarshalError = (*ErrCanceled)(nil)
+var _ UnmarshalError = (*ErrParserPanic)(nil)
 
 // freeUnmarshalError implements UnmarshalError, but does not contain any contextual information.
 type freeUnmarshalError string
@@ -34,6 +44,9 @@ func (err freeUnmarshalError) Error() string { return string(err) }
 var ErrDestIsNil UnmarshalError = freeUnmarshalError("Marshal.Unmarshal: dest is nil")
 var ErrNotPointerToStruct UnmarshalError = freeUnmarshalError("Marshal.Unmarshal: dest is not a pointer to a struct")
 
+var ErrSrcIsNil UnmarshalError = freeUnmarshalError("Marshal.Marshal: src is nil")
+var ErrSrcNotStruct UnmarshalError = freeUnmarshalError("Marshal.Marshal: src is not a struct or pointer to a struct")
+
 // ErrInlineNotStruct indicates that a destination field that is to be inlined, but is not a struct.
 // Implements UnmarshalError.
 type ErrInlineNotStruct struct {
@@ -80,6 +93,11 @@ type ErrFailedToParseField struct {
 	single               bool
 	tag                  reflect.StructTag
 
+	stage string
+
+	pos    Position
+	hasPos bool
+
 	cause error
 }
 
@@ -92,8 +110,157 @@ func (err ErrFailedToParseField) Tag() reflect.StructTag { return err.tag }
 // Unwrap provides compatibility for Go 1.13 error chains.
 func (err ErrFailedToParseField) Unwrap() error { return err.cause }
 
+// Position returns the position of the value that failed to parse.
+// This is only available when the source implements SourcePositioner.
+func (err ErrFailedToParseField) Position() (Position, bool) { return err.pos, err.hasPos }
+
+// Stage returns the name of the stage of a parser pipeline that failed.
+// For parsers that are not a pipeline, returns the same as Parser.
+func (err ErrFailedToParseField) Stage() string { return err.stage }
+
 func (err ErrFailedToParseField) Error() string {
-	return fmt.Sprintf("Marshal.Unmarshal: Failed to parse field %q: %s", err.dest, err.cause.Error())
+	var stage string
+	if err.stage != "" && err.stage != err.parser {
+		stage = fmt.Sprintf(" in stage %q", err.stage)
+	}
+	if err.hasPos {
+		return fmt.Sprintf("Marshal.Unmarshal: Failed to parse field %q%s (at %s): %s", err.dest, stage, err.pos, err.cause.Error())
+	}
+	return fmt.Sprintf("Marshal.Unmarshal: Failed to parse field %q%s: %s", err.dest, stage, err.cause.Error())
+}
+
+// stageError wraps an error that refers to a single stage of a parser pipeline.
+type stageError struct {
+	stage string
+	err   error
+}
+
+func (err stageError) Error() string {
+	return fmt.Sprintf("stage %q: %s", err.stage, err.err.Error())
+}
+
+// Unwrap provides compatibility for Go 1.13 error chains.
+func (err stageError) Unwrap() error { return err.err }
+
+// ErrValidationFailed indicates that a field was assigned a value, but failed a validation rule.
+// Implements UnmarshalError.
+type ErrValidationFailed struct {
+	dest, source, parser string
+	single               bool
+	tag                  reflect.StructTag
+
+	pos    Position
+	hasPos bool
+
+	Rule string // name of the rule that failed
+
+	cause error
+}
+
+func (err ErrValidationFailed) Dest() string           { return err.dest }
+func (err ErrValidationFailed) Source() string         { return err.source }
+func (err ErrValidationFailed) Parser() string         { return err.parser }
+func (err ErrValidationFailed) Single() bool           { return err.single }
+func (err ErrValidationFailed) Tag() reflect.StructTag { return err.tag }
+
+// Unwrap provides compatibility for Go 1.13 error chains.
+func (err ErrValidationFailed) Unwrap() error { return err.cause }
+
+// Position returns the position of the value that failed validation.
+// This is only available when the source implements SourcePositioner.
+func (err ErrValidationFailed) Position() (Position, bool) { return err.pos, err.hasPos }
+
+func (err ErrValidationFailed) Error() string {
+	if err.hasPos {
+		return fmt.Sprintf("Marshal.Unmarshal: Field %q failed validation %s (at %s): %s", err.dest, err.Rule, err.pos, err.cause.Error())
+	}
+	return fmt.Sprintf("Marshal.Unmarshal: Field %q failed validation %s: %s", err.dest, err.Rule, err.cause.Error())
+}
+
+// ErrCanceled indicates that the context passed to Marshal.UnmarshalContextual was done while processing a field.
+// Implements UnmarshalError.
+type ErrCanceled struct {
+	dest, source, parser string
+	single               bool
+	tag                  reflect.StructTag
+
+	cause error
+}
+
+func (err ErrCanceled) Dest() string           { return err.dest }
+func (err ErrCanceled) Source() string         { return err.source }
+func (err ErrCanceled) Parser() string         { return err.parser }
+func (err ErrCanceled) Single() bool           { return err.single }
+func (err ErrCanceled) Tag() reflect.StructTag { return err.tag }
+
+// Unwrap provides compatibility for Go 1.13 error chains.
+// It returns the error of the context, e.g. context.Canceled or context.DeadlineExceeded.
+func (err ErrCanceled) Unwrap() error { return err.cause }
+
+func (err ErrCanceled) Error() string {
+	return fmt.Sprintf("Marshal.Unmarshal: Canceled while processing field %q: %s", err.dest, err.cause.Error())
+}
+
+// ErrParserPanic indicates that a parser panicked while parsing a field.
+// Implements UnmarshalError.
+//
+// See Marshal.RepanicParsers to propagate the panic instead.
+type ErrParserPanic struct {
+	dest, source, parser string
+	single               bool
+	tag                  reflect.StructTag
+
+	stage string
+
+	// Value is the value that was recovered from the panic.
+	// It may be nil, e.g. for a parser calling panic(nil).
+	Value interface{}
+
+	// Stack is the stack trace of the goroutine at the time of the panic, see runtime/debug.Stack.
+	Stack []byte
+}
+
+func (err ErrParserPanic) Dest() string           { return err.dest }
+func (err ErrParserPanic) Source() string         { return err.source }
+func (err ErrParserPanic) Parser() string         { return err.parser }
+func (err ErrParserPanic) Single() bool           { return err.single }
+func (err ErrParserPanic) Tag() reflect.StructTag { return err.tag }
+
+// Stage returns the name of the stage of a parser pipeline that panicked.
+// For parsers that are not a pipeline, returns the same as Parser.
+func (err ErrParserPanic) Stage() string { return err.stage }
+
+// Unwrap provides compatibility for Go 1.13 error chains.
+// It returns the recovered value if it is an error, and nil otherwise.
+func (err ErrParserPanic) Unwrap() error {
+	cause, _ := err.Value.(error)
+	return cause
+}
+
+func (err ErrParserPanic) Error() string {
+	var stage string
+	if err.stage != "" && err.stage != err.parser {
+		stage = fmt.Sprintf(" in stage %q", err.stage)
+	}
+	return fmt.Sprintf("Marshal.Unmarshal: Parser panicked while parsing field %q%s: %v", err.dest, stage, err.Value)
+}
+
+// ErrMissingRequired indicates that the key of a required field does not exist in the source.
+// Implements UnmarshalError.
+type ErrMissingRequired struct {
+	dest, source, parser string
+	single               bool
+	tag                  reflect.StructTag
+}
+
+func (err ErrMissingRequired) Dest() string           { return err.dest }
+func (err ErrMissingRequired) Source() string         { return err.source }
+func (err ErrMissingRequired) Parser() string         { return err.parser }
+func (err ErrMissingRequired) Single() bool           { return err.single }
+func (err ErrMissingRequired) Tag() reflect.StructTag { return err.tag }
+
+func (err ErrMissingRequired) Error() string {
+	return fmt.Sprintf("Marshal.Unmarshal: Missing required key %q for field %q", err.source, err.dest)
 }
 
 // ErrWrongDestType intends that the returned value can not be assigned or converted to the destination field.
@@ -133,7 +300,171 @@ func (err ErrWrongDestType) Error() string {
 	return fmt.Sprintf("Marshal.Unmarshal: Failed to process value for field %q: Parser returned type %s, but cannot %s to %s%s", err.dest, err.ReturnedType, err.DestType, verb, suffix)
 }
 
+// ErrUnknownFormatter indicates that an unknown formatter was encountered.
+// Implements UnmarshalError.
+type ErrUnknownFormatter struct {
+	dest, source, parser string
+	tag                  reflect.StructTag
+	cause                error
+}
+
+func (err ErrUnknownFormatter) Dest() string           { return err.dest }
+func (err ErrUnknownFormatter) Source() string         { return err.source }
+func (err ErrUnknownFormatter) Parser() string         { return err.parser }
+func (ErrUnknownFormatter) Single() bool               { return false }
+func (err ErrUnknownFormatter) Tag() reflect.StructTag { return err.tag }
+
+func (err ErrUnknownFormatter) Error() string {
+	return fmt.Sprintf("Marshal.Marshal: Source field %q has unknown formatter %s: %s", err.dest, err.parser, err.cause.Error())
+}
+
+// Unwrap provides compatibility for Go 1.13 error chains
+func (err ErrUnknownFormatter) Unwrap() error { return err.cause }
+
+// ErrFailedToFormatField indicates that Marshal.Marshal failed to format a field.
+// Implements UnmarshalError.
+type ErrFailedToFormatField struct {
+	dest, source, parser string
+	single               bool
+	tag                  reflect.StructTag
+
+	cause error
+}
+
+func (err ErrFailedToFormatField) Dest() string           { return err.dest }
+func (err ErrFailedToFormatField) Source() string         { return err.source }
+func (err ErrFailedToFormatField) Parser() string         { return err.parser }
+func (err ErrFailedToFormatField) Single() bool           { return err.single }
+func (err ErrFailedToFormatField) Tag() reflect.StructTag { return err.tag }
+
+// Unwrap provides compatibility for Go 1.13 error chains.
+func (err ErrFailedToFormatField) Unwrap() error { return err.cause }
+
+func (err ErrFailedToFormatField) Error() string {
+	return fmt.Sprintf("Marshal.Marshal: Failed to format field %q: %s", err.dest, err.cause.Error())
+}
+
+// ErrUnknownSource indicates that a field selected a sub-source that does not exist.
+// Implements UnmarshalError.
+type ErrUnknownSource struct {
+	dest, source, parser string
+	tag                  reflect.StructTag
+
+	Name string // the name of the sub-source
+}
+
+func (err ErrUnknownSource) Dest() string           { return err.dest }
+func (err ErrUnknownSource) Source() string         { return err.source }
+func (err ErrUnknownSource) Parser() string         { return err.parser }
+func (ErrUnknownSource) Single() bool               { return false }
+func (err ErrUnknownSource) Tag() reflect.StructTag { return err.tag }
+
+func (err ErrUnknownSource) Error() string {
+	return fmt.Sprintf("Marshal.Unmarshal: Destination field %q selects unknown source %q", err.dest, err.Name)
+}
+
+// ErrInvalidRequest indicates that Marshal.UnmarshalRequest failed to parse the request.
+// Implements UnmarshalError, but does not contain any contextual information.
+type ErrInvalidRequest struct {
+	cause error
+}
+
+func (ErrInvalidRequest) Dest() string           { return "" }
+func (ErrInvalidRequest) Source() string         { return "" }
+func (ErrInvalidRequest) Parser() string         { return "" }
+func (ErrInvalidRequest) Single() bool           { return false }
+func (ErrInvalidRequest) Tag() reflect.StructTag { return "" }
+
+// Unwrap provides compatibility for Go 1.13 error chains.
+func (err ErrInvalidRequest) Unwrap() error { return err.cause }
+
+func (err ErrInvalidRequest) Error() string {
+	return fmt.Sprintf("Marshal.UnmarshalRequest: Failed to parse request: %s", err.cause.Error())
+}
+
+// ErrCollected holds all errors that occurred when Marshal.CollectErrors is set.
+// It contains at least one error.
+//
+// Implements UnmarshalError, using the state of the first error.
+// It can be inspected using errors.Is and errors.As, which match if any of the collected errors matches.
+type ErrCollected struct {
+	Errors []UnmarshalError
+}
+
+func (err ErrCollected) Dest() string           { return err.Errors[0].Dest() }
+func (err ErrCollected) Source() string         { return err.Errors[0].Source() }
+func (err ErrCollected) Parser() string         { return err.Errors[0].Parser() }
+func (err ErrCollected) Single() bool           { return err.Errors[0].Single() }
+func (err ErrCollected) Tag() reflect.StructTag { return err.Errors[0].Tag() }
+
+func (err ErrCollected) Error() string {
+	messages := make([]string, len(err.Errors))
+	for i, e := range err.Errors {
+		messages[i] = e.Error()
+	}
+	return fmt.Sprintf("%d error(s) occurred: %s", len(err.Errors), strings.Join(messages, "; "))
+}
+
+// Is provides compatibility for Go 1.13 error chains.
+func (err ErrCollected) Is(target error) bool {
+	for _, e := range err.Errors {
+		if errors.Is(e, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As provides compatibility for Go 1.13 error chains.
+func (err ErrCollected) As(target interface{}) bool {
+	for _, e := range err.Errors {
+		if errors.As(e, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Unwrap provides compatibility for Go 1.20 error trees.
+func (err ErrCollected) Unwrap() []error {
+	errs := make([]error, len(err.Errors))
+	for i, e := range err.Errors {
+		errs[i] = e
+	}
+	return errs
+}
+
+// errCollector collects errors during marshaling and unmarshaling.
+type errCollector struct {
+	collect bool
+	errors  []UnmarshalError
+}
+
+// Add reports err to the collector.
+// When the collector collects errors, it stores err and returns nil.
+// Otherwise err is returned unchanged.
+func (c *errCollector) Add(err UnmarshalError) error {
+	if !c.collect {
+		return err
+	}
+	c.errors = append(c.errors, err)
+	return nil
+}
+
+// Err returns an ErrCollected holding the collected errors, or nil if no errors were collected.
+func (c *errCollector) Err() error {
+	if len(c.errors) == 0 {
+		return nil
+	}
+	return ErrCollected{Errors: c.errors}
+}
+
 // the errors below never have any information associated with it.
 
 var ErrUnknownParserType = errors.New("Marshal.Unmarshal: unknown parser type")
 var ErrBothParserType = errors.New("Marshal.Unmarshal: parser type in both Single and Multi")
+
+var ErrUnknownValidator = errors.New("Marshal.Unmarshal: unknown validator")
+
+var ErrUnknownFormatterType = errors.New("Marshal.Marshal: unknown formatter type")
+var ErrBothFormatterType = errors.New("Marshal.Marshal: formatter type in both Single and Multi")
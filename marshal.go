@@ -0,0 +1,328 @@
+package stringreader
+
+import "reflect"
+
+// SingleFormatter is a function that formats a value into a single string.
+// It is the inverse of a SingleParser.
+//
+// When ok is false, the value is omitted from the result.
+type SingleFormatter = func(value interface{}, ctx UnmarshalContext) (result string, ok bool, err error)
+
+// MultiFormatter is a function that formats a value into multiple strings.
+// It is the inverse of a MultiParser.
+//
+// When ok is false, the value is omitted from the result.
+type MultiFormatter = func(value interface{}, ctx UnmarshalContext) (result []string, ok bool, err error)
+
+// MarshalState marshals data from src into a pair of new sources.
+// Any non-nil error returned implements UnmarshalError.
+//
+// Src must be a struct or a non-nil pointer to a struct; if this is not the case, ErrSrcIsNil or ErrSrcNotStruct is returned.
+//
+// Fields are processed using the same rules as UnmarshalState.
+// Instead of a parser, the formatter with the same name in m.SingleFormatters or m.MultiFormatters is used.
+// For a parser pipeline, only the formatter of the last stage is used.
+// The result of a SingleFormatter is stored in the SourceSingleMap, the result of a MultiFormatter in the SourceMultiMap.
+// Fields with a type parser use the formatter for the same type in m.TypeFormatters or m.TypeMultiFormatters instead.
+// Fields that parse themselves are formatted using encoding.TextMarshaler or fmt.Stringer.
+// When a field is to be inlined, but is a nil pointer to a struct, it is skipped.
+// Prefixes of inlined fields are applied to the keys of the nested fields.
+// Sub-sources selected using m.SourceTag are ignored.
+//
+// Like UnmarshalState, errors are collected when m.CollectErrors is set.
+func (m Marshal) MarshalState(src interface{}, data ParsingData) (SourceSingleMap, SourceMultiMap, error) {
+	if src == nil {
+		return nil, nil, ErrSrcIsNil
+	}
+
+	// the source may be a struct or a pointer to a struct
+	sValue := reflect.ValueOf(src)
+	if sValue.Kind() == reflect.Ptr {
+		if sValue.IsNil() {
+			return nil, nil, ErrSrcIsNil
+		}
+		sValue = sValue.Elem()
+	}
+	if sValue.Kind() != reflect.Struct {
+		return nil, nil, ErrSrcNotStruct
+	}
+
+	single := make(SourceSingleMap)
+	multi := make(SourceMultiMap)
+
+	collector := &errCollector{collect: m.CollectErrors}
+	if err := m.marshalStruct(sValue, nil, nil, "", data, single, multi, collector); err != nil {
+		return nil, nil, err
+	}
+	if err := collector.Err(); err != nil {
+		return nil, nil, err
+	}
+	return single, multi, nil
+}
+
+// Marshal is like MarshalState, but with a nil context
+func (m Marshal) Marshal(src interface{}) (SourceSingleMap, SourceMultiMap, error) {
+	return m.MarshalState(src, ParsingData{})
+}
+
+// marshalStruct marshals the struct sValue into single and multi, prefixing each key with prefix.
+// Path holds the names of the inlined fields containing sValue.
+// Shadow is the embedding of sValue; when nil, the embedding of its plan is used.
+// Errors are reported to collector; the first error that collector does not collect is returned.
+func (m Marshal) marshalStruct(sValue reflect.Value, path []string, shadow *embedding, prefix string, data ParsingData, single SourceSingleMap, multi SourceMultiMap, collector *errCollector) error {
+	plan := loadPlan(sValue.Type(), m.planConfig())
+	if shadow == nil {
+		shadow = plan.embedding
+	}
+
+	// grab a new context item from the pool
+	// and store context data with it.
+	ctx := contextPool.Get().(*unmarshalContext)
+	defer contextPool.Put(ctx)
+
+	ctx.data = data
+	defer ctx.Reset()
+
+	for i := range plan.fields {
+		fp := &plan.fields[i]
+
+		// skip fields hidden by other fields of embedded structs
+		if shadow.isHidden(i) {
+			continue
+		}
+
+		fValue := sValue.Field(fp.index)
+
+		ctx.dest = fp.field.Name
+		ctx.tag = fp.field.Tag
+		ctx.field = fp.field
+		ctx.parser = fp.parser
+		ctx.source = fp.source
+		if fp.mapped && m.NameMapper != nil {
+			ctx.source = m.NameMapper(fp.field, path)
+		}
+
+		// check if the inline parser is being requested.
+		// and if so, recurse into the struct.
+		if fp.inline {
+			if fp.inlineErr {
+				if err := collector.Add(ErrInlineNotStruct{
+					dest:   ctx.dest,
+					parser: ctx.parser,
+					tag:    ctx.tag,
+				}); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if fp.inlinePtr {
+				// nothing to write for a nil pointer
+				if fValue.IsNil() {
+					continue
+				}
+				fValue = fValue.Elem()
+			}
+
+			if err := m.marshalStruct(fValue, m.innerPath(fp, path), shadow.child(i), prefix+fp.prefix, data, single, multi, collector); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// figure out if we have a single or a multi formatter
+		// a field without any formatter is skipped
+		rf, err := m.resolveFormatter(fp)
+		if rf.name == "" {
+			continue
+		}
+		ctx.parser, ctx.stage, ctx.args = rf.name, rf.stage, rf.args
+		singleFormatter, multiFormatter := rf.single, rf.multi
+		if err != nil {
+			if err := collector.Add(ErrUnknownFormatter{
+				dest:   ctx.dest,
+				source: ctx.source,
+				parser: ctx.parser,
+				tag:    ctx.tag,
+
+				cause: err,
+			}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// format and store the value
+		var fOK bool
+		var fErr error
+
+		switch {
+		case singleFormatter != nil:
+			ctx.single = true
+
+			var result string
+			result, fOK, fErr = singleFormatter(fValue.Interface(), ctx)
+			if fErr == nil && fOK {
+				single[prefix+ctx.source] = result
+			}
+		case multiFormatter != nil:
+			ctx.single = false
+
+			var result []string
+			result, fOK, fErr = multiFormatter(fValue.Interface(), ctx)
+			if fErr == nil && fOK {
+				multi[prefix+ctx.source] = result
+			}
+		}
+		if fErr != nil {
+			if err := collector.Add(ErrFailedToFormatField{
+				dest:   ctx.dest,
+				source: ctx.source,
+				parser: ctx.parser,
+				single: ctx.single,
+				tag:    ctx.tag,
+
+				cause: fErr,
+			}); err != nil {
+				return err
+			}
+			continue
+		}
+	}
+	return nil
+}
+
+// GetFormatter finds either a single or multi formatter, and performs appropriate error checking
+func (m Marshal) GetFormatter(name string) (single SingleFormatter, multi MultiFormatter, err error) {
+	var singleOK, multiOK bool
+
+	// find non-nil values in the formatters!
+	single, singleOK = m.SingleFormatters[name]
+	multi, multiOK = m.MultiFormatters[name]
+
+	singleOK = singleOK && single != nil
+	multiOK = multiOK && multi != nil
+
+	// ensure that we have exactly one value, or fail
+	if singleOK && multiOK {
+		return nil, nil, ErrBothFormatterType
+	}
+
+	if !(singleOK || multiOK) {
+		return nil, nil, ErrUnknownFormatterType
+	}
+
+	return
+}
+
+// GetTypeFormatter finds either a single or multi formatter for the type typ, and performs appropriate error checking
+func (m Marshal) GetTypeFormatter(typ reflect.Type) (single SingleFormatter, multi MultiFormatter, err error) {
+	var singleOK, multiOK bool
+
+	single, singleOK = m.TypeFormatters[typ]
+	multi, multiOK = m.TypeMultiFormatters[typ]
+
+	singleOK = singleOK && single != nil
+	multiOK = multiOK && multi != nil
+
+	if singleOK && multiOK {
+		return nil, nil, ErrBothFormatterType
+	}
+
+	if !(singleOK || multiOK) {
+		return nil, nil, ErrUnknownFormatterType
+	}
+
+	return
+}
+
+// resolvedFormatter is the result of resolving the formatter of a field.
+type resolvedFormatter struct {
+	name  string            // name of the formatter, see UnmarshalContext.Parser
+	stage string            // name of the last stage of a pipeline, see UnmarshalContext.Stage
+	args  map[string]string // arguments to the formatter, see UnmarshalContext.Args
+
+	single SingleFormatter
+	multi  MultiFormatter
+}
+
+// resolveFormatter resolves the formatter of fp.
+// It uses the same order of precedence as resolveParser.
+// For a parser pipeline, the formatter of the last stage is used.
+func (m Marshal) resolveFormatter(fp *fieldPlan) (rf resolvedFormatter, err error) {
+	if !fp.tagged {
+		rf.single, rf.multi, err = m.GetTypeFormatter(fp.field.Type)
+		if err != ErrUnknownFormatterType {
+			rf.name = fp.field.Type.String()
+			rf.stage = rf.name
+			return rf, err
+		}
+		err = nil
+
+		if fp.self {
+			rf.name, rf.stage, rf.single = fp.parser, fp.parser, formatSelf
+			return rf, nil
+		}
+	}
+
+	rf.name = fp.parser
+	if rf.name == "" {
+		return rf, nil
+	}
+	if fp.parserErr != nil {
+		return rf, fp.parserErr
+	}
+
+	last := fp.stages[len(fp.stages)-1]
+	rf.stage, rf.args = last.name, last.args
+
+	rf.single, rf.multi, err = m.GetFormatter(last.name)
+	if err != nil && len(fp.stages) > 1 {
+		err = stageError{stage: last.name, err: err}
+	}
+	return rf, err
+}
+
+// RegisterSingleFormatter registers a new SingleFormatter with m.
+//
+// Formatter should not be nil, and should not exist in m.MultiFormatters.
+// No checking of these conditions is performed; they should be ensured by the caller.
+func (m *Marshal) RegisterSingleFormatter(name string, formatter SingleFormatter) {
+	if m.SingleFormatters == nil {
+		m.SingleFormatters = make(map[string]SingleFormatter)
+	}
+	m.SingleFormatters[name] = formatter
+}
+
+// RegisterMultiFormatter registers a new MultiFormatter with m.
+//
+// Formatter should not be nil, and should not exist in m.SingleFormatters.
+// No checking of these conditions is performed; they should be ensured by the caller.
+func (m *Marshal) RegisterMultiFormatter(name string, formatter MultiFormatter) {
+	if m.MultiFormatters == nil {
+		m.MultiFormatters = make(map[string]MultiFormatter)
+	}
+	m.MultiFormatters[name] = formatter
+}
+
+// RegisterTypeFormatter registers a new SingleFormatter for fields of type typ with m.
+//
+// Formatter should not be nil, and typ should not exist in m.TypeMultiFormatters.
+// No checking of these conditions is performed; they should be ensured by the caller.
+func (m *Marshal) RegisterTypeFormatter(typ reflect.Type, formatter SingleFormatter) {
+	if m.TypeFormatters == nil {
+		m.TypeFormatters = make(map[reflect.Type]SingleFormatter)
+	}
+	m.TypeFormatters[typ] = formatter
+}
+
+// RegisterTypeMultiFormatter registers a new MultiFormatter for fields of type typ with m.
+//
+// Formatter should not be nil, and typ should not exist in m.TypeFormatters.
+// No checking of these conditions is performed; they should be ensured by the caller.
+func (m *Marshal) RegisterTypeMultiFormatter(typ reflect.Type, formatter MultiFormatter) {
+	if m.TypeMultiFormatters == nil {
+		m.TypeMultiFormatters = make(map[reflect.Type]MultiFormatter)
+	}
+	m.TypeMultiFormatters[typ] = formatter
+}
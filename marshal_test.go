@@ -0,0 +1,183 @@
+package stringreader_test
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/tkw1536/stringreader"
+)
+
+func TestMarshal_Marshal(t *testing.T) {
+
+	// reset_formatters resets the formatters for m
+	reset_formatters := func(m *stringreader.Marshal) {
+		m.SingleFormatters = nil
+		m.MultiFormatters = nil
+
+		m.RegisterSingleFormatter("string", func(value interface{}, ctx stringreader.UnmarshalContext) (string, bool, error) {
+			return value.(string), true, nil
+		})
+		m.RegisterSingleFormatter("omitempty", func(value interface{}, ctx stringreader.UnmarshalContext) (string, bool, error) {
+			s := value.(string)
+			return s, s != "", nil
+		})
+		m.RegisterMultiFormatter("list", func(value interface{}, ctx stringreader.UnmarshalContext) ([]string, bool, error) {
+			return value.([]string), true, nil
+		})
+	}
+
+	type Nested struct {
+		Value string `name:"nested"`
+	}
+
+	tests := []struct {
+		name       string
+		marshal    stringreader.Marshal // ignores the existing formatters
+		src        interface{}
+		wantSingle stringreader.SourceSingleMap
+		wantMulti  stringreader.SourceMultiMap
+		wantErr    bool
+	}{
+		{
+			name: "single and multi fields",
+			marshal: stringreader.Marshal{
+				NameTag:       "name",
+				ParserTag:     "parser",
+				DefaultParser: "string",
+			},
+			src: struct {
+				Plain  string
+				Named  string   `name:"named"`
+				Empty  string   `parser:"omitempty"`
+				Values []string `name:"values" parser:"list"`
+			}{
+				Plain:  "plain",
+				Named:  "named value",
+				Values: []string{"a", "b"},
+			},
+			wantSingle: stringreader.SourceSingleMap{
+				"Plain": "plain",
+				"named": "named value",
+			},
+			wantMulti: stringreader.SourceMultiMap{
+				"values": {"a", "b"},
+			},
+		},
+		{
+			name: "inlined structs",
+			marshal: stringreader.Marshal{
+				NameTag:       "name",
+				ParserTag:     "parser",
+				DefaultParser: "string",
+				InlineParser:  "inline",
+			},
+			src: &struct {
+				Inline  Nested  `parser:"inline"`
+				Pointer *Nested `parser:"inline"`
+			}{
+				Inline: Nested{Value: "inline"},
+			},
+			wantSingle: stringreader.SourceSingleMap{
+				"nested": "inline",
+			},
+			wantMulti: stringreader.SourceMultiMap{},
+		},
+		{
+			name: "unknown formatter",
+			marshal: stringreader.Marshal{
+				ParserTag: "parser",
+			},
+			src: struct {
+				Value string `parser:"unknown"`
+			}{},
+			wantErr: true,
+		},
+		{
+			name:    "not a struct",
+			src:     "hello world",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := tt.marshal
+			reset_formatters(&m)
+
+			gotSingle, gotMulti, err := m.Marshal(tt.src)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("wantErr = true, err = nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("Marshal.Marshal() err = %s, want = nil", err.Error())
+			}
+
+			if !reflect.DeepEqual(gotSingle, tt.wantSingle) {
+				t.Errorf("Marshal.Marshal() single = %v, want = %v", gotSingle, tt.wantSingle)
+			}
+			if !reflect.DeepEqual(gotMulti, tt.wantMulti) {
+				t.Errorf("Marshal.Marshal() multi = %v, want = %v", gotMulti, tt.wantMulti)
+			}
+		})
+	}
+}
+
+func ExampleMarshal_Marshal() {
+
+	marshal := &stringreader.Marshal{
+		NameTag: "read",
+
+		ParserTag:     "type",
+		DefaultParser: "string",
+	}
+
+	type UserProfile struct {
+		User     string `read:"user"`
+		Hostname string `read:"host"`
+		Port     uint16 `read:"port" type:"port"`
+	}
+
+	// register parsers and formatters under the same names
+	marshal.RegisterSingleParser("string", func(value string, ok bool, ctx stringreader.UnmarshalContext) (interface{}, error) {
+		return value, nil
+	})
+	marshal.RegisterSingleFormatter("string", func(value interface{}, ctx stringreader.UnmarshalContext) (string, bool, error) {
+		return value.(string), true, nil
+	})
+
+	marshal.RegisterSingleParser("port", func(value string, ok bool, ctx stringreader.UnmarshalContext) (interface{}, error) {
+		sport, err := strconv.ParseUint(value, 10, 16)
+		return uint16(sport), err
+	})
+	marshal.RegisterSingleFormatter("port", func(value interface{}, ctx stringreader.UnmarshalContext) (string, bool, error) {
+		return strconv.FormatUint(uint64(value.(uint16)), 10), true, nil
+	})
+
+	// marshal a profile
+	single, _, err := marshal.Marshal(UserProfile{
+		User:     "johnsmith",
+		Hostname: "localhost",
+		Port:     2222,
+	})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(single["user"], single["host"], single["port"])
+
+	// and read it back again
+	var profile UserProfile
+	if err := marshal.UnmarshalSingle(&profile, single); err != nil {
+		panic(err)
+	}
+	fmt.Printf("%v\n", &profile)
+
+	// Output:
+	// johnsmith localhost 2222
+	// &{johnsmith localhost 2222}
+}
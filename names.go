@@ -0,0 +1,105 @@
+package stringreader
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// NameMapper maps a struct field to the key that is read from a source.
+// It is used when a field has no name tag, see Marshal.NameMapper.
+//
+// Path contains the names of the inlined fields that contain field, outermost first.
+// It must not be modified or retained.
+type NameMapper = func(field reflect.StructField, path []string) string
+
+// SnakeCase is a NameMapper that maps field names to snake_case, e.g. "DatabaseURL" becomes "database_url".
+// The path is ignored.
+func SnakeCase(field reflect.StructField, path []string) string {
+	return joinWords(splitWords(field.Name), "_", strings.ToLower)
+}
+
+// ScreamingSnakeCase is a NameMapper that maps field names to SCREAMING_SNAKE_CASE, e.g. "DatabaseURL" becomes "DATABASE_URL".
+// The path is ignored.
+func ScreamingSnakeCase(field reflect.StructField, path []string) string {
+	return joinWords(splitWords(field.Name), "_", strings.ToUpper)
+}
+
+// KebabCase is a NameMapper that maps field names to kebab-case, e.g. "DatabaseURL" becomes "database-url".
+// The path is ignored.
+func KebabCase(field reflect.StructField, path []string) string {
+	return joinWords(splitWords(field.Name), "-", strings.ToLower)
+}
+
+// CamelCase is a NameMapper that maps field names to camelCase, e.g. "DatabaseURL" becomes "databaseURL".
+// Only the first word is changed to lower case, the case of other words is kept.
+// The path is ignored.
+func CamelCase(field reflect.StructField, path []string) string {
+	words := splitWords(field.Name)
+	if len(words) == 0 {
+		return ""
+	}
+	words[0] = strings.ToLower(words[0])
+	return strings.Join(words, "")
+}
+
+// JoinPath returns a NameMapper that prefixes the result of mapper with the path of a field, separated by sep.
+// Each element of the path is mapped by calling mapper with a StructField that only has its Name set.
+//
+// For example, JoinPath("_", ScreamingSnakeCase) maps the field "URL" inside of the inlined field "Database" to "DATABASE_URL".
+func JoinPath(sep string, mapper NameMapper) NameMapper {
+	return func(field reflect.StructField, path []string) string {
+		parts := make([]string, 0, len(path)+1)
+		for _, name := range path {
+			parts = append(parts, mapper(reflect.StructField{Name: name}, nil))
+		}
+		parts = append(parts, mapper(field, path))
+		return strings.Join(parts, sep)
+	}
+}
+
+// DottedPath is like JoinPath, but always uses a dot as a separator.
+func DottedPath(mapper NameMapper) NameMapper {
+	return JoinPath(".", mapper)
+}
+
+// splitWords splits a Go identifier into words.
+// A new word starts at every underscore, at every upper case letter following a lower case letter or digit,
+// and at the last upper case letter of an acronym that is followed by a lower case letter.
+func splitWords(name string) []string {
+	runes := []rune(name)
+
+	var words []string
+	start := 0
+	flush := func(end int) {
+		if end > start {
+			words = append(words, string(runes[start:end]))
+		}
+	}
+
+	for i, r := range runes {
+		switch {
+		case r == '_' || r == '-':
+			flush(i)
+			start = i + 1
+		case i > start && unicode.IsUpper(r):
+			prev := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower) {
+				flush(i)
+				start = i
+			}
+		}
+	}
+	flush(len(runes))
+
+	return words
+}
+
+// joinWords joins words using sep, after applying convert to each of them.
+func joinWords(words []string, sep string, convert func(string) string) string {
+	for i, word := range words {
+		words[i] = convert(word)
+	}
+	return strings.Join(words, sep)
+}
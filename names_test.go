@@ -0,0 +1,70 @@
+package stringreader
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestNameMappers(t *testing.T) {
+	tests := []struct {
+		name      string
+		mapper    NameMapper
+		field     string
+		path      []string
+		wantValue string
+	}{
+		{"snake_case", SnakeCase, "DatabaseURL", nil, "database_url"},
+		{"snake_case acronym first", SnakeCase, "URLPath", nil, "url_path"},
+		{"snake_case digits", SnakeCase, "Server2Port", nil, "server2_port"},
+		{"snake_case underscore", SnakeCase, "Already_Snake", nil, "already_snake"},
+		{"SCREAMING_SNAKE", ScreamingSnakeCase, "DatabaseURL", nil, "DATABASE_URL"},
+		{"kebab-case", KebabCase, "MaxIdleConns", nil, "max-idle-conns"},
+		{"camelCase", CamelCase, "DatabaseURL", nil, "databaseURL"},
+		{"camelCase acronym first", CamelCase, "HTTPServer", nil, "httpServer"},
+		{"dotted path", DottedPath(SnakeCase), "MaxConns", []string{"Database", "PoolConfig"}, "database.pool_config.max_conns"},
+		{"joined path", JoinPath("_", ScreamingSnakeCase), "URL", []string{"Database"}, "DATABASE_URL"},
+		{"joined empty path", JoinPath("_", ScreamingSnakeCase), "URL", nil, "URL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.mapper(reflect.StructField{Name: tt.field}, tt.path)
+			if got != tt.wantValue {
+				t.Errorf("NameMapper(%q, %v) = %q, want = %q", tt.field, tt.path, got, tt.wantValue)
+			}
+		})
+	}
+}
+
+func ExampleNameMapper() {
+	var marshal Marshal
+	marshal.ParserTag = "type"
+	marshal.InlineParser = "inline"
+	marshal.NameMapper = JoinPath("_", ScreamingSnakeCase)
+	marshal.RegisterStandardParsers()
+
+	type Database struct {
+		URL      string
+		MaxConns int
+	}
+
+	// no name tags are needed
+	type Config struct {
+		ListenAddress string
+		Database      Database `type:"inline"`
+	}
+
+	var config Config
+	err := marshal.Unmarshal(&config, SourceEnv{Environ: []string{
+		"LISTEN_ADDRESS=:8080",
+		"DATABASE_URL=postgres://localhost",
+		"DATABASE_MAX_CONNS=10",
+	}})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("%+v\n", config)
+
+	// Output:
+	// {ListenAddress::8080 Database:{URL:postgres://localhost MaxConns:10}}
+}
@@ -0,0 +1,625 @@
+package stringreader
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// plan holds the pre-computed information required to (un)marshal a specific struct type.
+//
+// A plan only depends on the struct type and the tag configuration of a Marshal (see planConfig).
+// The parsers resolved for each field additionally depend on the parser maps of a Marshal.
+// They are cached within the plan as well, and resolved again when the maps change, see resolvedParsers.
+//
+// Formatters and validators are not part of a plan, and are looked up every time they are used.
+// Names produced by Marshal.NameMapper are not cached either, and it is called for every processed field.
+type plan struct {
+	fields []fieldPlan
+
+	// embedding of the fields, when the struct is not itself embedded in another struct
+	embedding *embedding
+
+	// parsers holds the *parserCache of the most recently used parser maps
+	parsers atomic.Value
+}
+
+// fieldPlan holds the pre-computed information for a single field of a struct.
+// Fields that are always skipped do not have a fieldPlan.
+type fieldPlan struct {
+	index int // index of the field in the struct
+	field reflect.StructField
+
+	parser string // name of the parser to use, empty when only a type parser may apply
+	tagged bool   // parser was given explicitly using the parser tag
+	self   bool   // parser is the method of the field type, see selfParserOf
+	source string // key to read from the source, empty for inlined fields
+	mapped bool   // source is the name of the field, and may be replaced by Marshal.NameMapper
+	sub    string // name of the sub-source to select, if any
+
+	stages     []parserStage // stages of the parser pipeline, a single stage when there is no pipeline
+	typeStages []parserStage // single stage named after the field type, used when a type parser applies
+	parserErr  error         // syntax error in the parser reference, if any
+
+	required bool // is this field (or inlined struct) required?
+
+	def    string // default value, when hasDef is true
+	hasDef bool   // is there a default value?
+
+	rules []validationRule // validation rules to run after assignment
+
+	inline    bool   // is this field to be inlined?
+	embedded  bool   // when inlining, is this an embedded struct that is inlined automatically?
+	inlinePtr bool   // when inlining, is this a pointer to a struct?
+	inlineErr bool   // when inlining, is this field not a struct?
+	prefix    string // when inlining, the prefix for nested keys
+
+	zero reflect.Value // zero value of the field type
+}
+
+// planConfig holds the parts of a Marshal that a plan depends on.
+type planConfig struct {
+	NameTag       string
+	StrictNameTag bool
+
+	ParserTag     string
+	DefaultParser string
+	InlineParser  string
+
+	SourceTag   string
+	PrefixTag   string
+	DefaultTag  string
+	ValidateTag string
+}
+
+func (m Marshal) planConfig() planConfig {
+	return planConfig{
+		NameTag:       m.NameTag,
+		StrictNameTag: m.StrictNameTag,
+
+		ParserTag:     m.ParserTag,
+		DefaultParser: m.DefaultParser,
+		InlineParser:  m.InlineParser,
+
+		SourceTag:   m.SourceTag,
+		PrefixTag:   m.PrefixTag,
+		DefaultTag:  m.DefaultTag,
+		ValidateTag: m.ValidateTag,
+	}
+}
+
+// planKey is used to cache plans.
+type planKey struct {
+	typ    reflect.Type
+	config planConfig
+}
+
+// planCache caches plans, keyed by planKey.
+// It is safe for concurrent use.
+var planCache sync.Map
+
+// loadPlan returns the plan for the struct type typ.
+// It is a variable so that benchmarks can compare against compilePlan.
+var loadPlan = cachedPlan
+
+// cachedPlan returns the plan for typ and config, compiling and caching it if necessary.
+func cachedPlan(typ reflect.Type, config planConfig) *plan {
+	key := planKey{typ: typ, config: config}
+	if p, ok := planCache.Load(key); ok {
+		return p.(*plan)
+	}
+
+	// two concurrent callers may both compile the plan.
+	// this is harmless, as both will be identical.
+	p, _ := planCache.LoadOrStore(key, compilePlan(typ, config))
+	return p.(*plan)
+}
+
+// parserGeneration is incremented whenever a parser is registered with any Marshal.
+// It invalidates all cached parsers, see resolvedParsers.
+var parserGeneration uint64
+
+// parsersChanged invalidates all cached parsers.
+func parsersChanged() {
+	atomic.AddUint64(&parserGeneration, 1)
+}
+
+// parserCache holds the parsers resolved for the fields of a plan.
+type parserCache struct {
+	generation uint64     // value of parserGeneration when the parsers were resolved
+	maps       [4]uintptr // identities of the parser maps the parsers were resolved from
+
+	fields []resolvedField // resolved parser of each field, unused for inlined fields
+}
+
+// resolvedField is the result of resolveParser for a single field.
+type resolvedField struct {
+	rp    resolvedParser
+	funcs []parserFuncs
+	err   error
+}
+
+// resolvedParsers returns the parsers of the fields of p, as resolved by m.resolveParser.
+//
+// The parsers are cached within p, and resolved again when a parser has been registered since, or when m uses different parser maps.
+// Changes that are made to the parser maps directly, instead of using the Register methods, are not detected.
+func (m Marshal) resolvedParsers(p *plan) *parserCache {
+	generation := atomic.LoadUint64(&parserGeneration)
+	maps := [4]uintptr{
+		reflect.ValueOf(m.SingleParsers).Pointer(),
+		reflect.ValueOf(m.MultiParsers).Pointer(),
+		reflect.ValueOf(m.TypeParsers).Pointer(),
+		reflect.ValueOf(m.TypeMultiParsers).Pointer(),
+	}
+
+	if cache, ok := p.parsers.Load().(*parserCache); ok && cache.generation == generation && cache.maps == maps {
+		return cache
+	}
+
+	cache := &parserCache{
+		generation: generation,
+		maps:       maps,
+		fields:     make([]resolvedField, len(p.fields)),
+	}
+	for i := range p.fields {
+		if fp := &p.fields[i]; !fp.inline {
+			field := &cache.fields[i]
+			field.rp, field.funcs, field.err = m.resolveParser(fp)
+		}
+	}
+
+	p.parsers.Store(cache)
+	return cache
+}
+
+// compilePlan compiles a new plan for the struct type typ.
+func compilePlan(typ reflect.Type, config planConfig) *plan {
+	fields := compileFields(typ, config)
+	return &plan{
+		fields:    fields,
+		embedding: computeEmbedding(typ, fields, config),
+	}
+}
+
+// compileFields compiles the plans for all fields of the struct type typ that are not always skipped.
+func compileFields(typ reflect.Type, config planConfig) []fieldPlan {
+	num := typ.NumField()
+
+	fields := make([]fieldPlan, 0, num)
+	for i := 0; i < num; i++ {
+		if fp, ok := compileField(typ.Field(i), i, config); ok {
+			fields = append(fields, fp)
+		}
+	}
+	return fields
+}
+
+// compileField compiles the plan for a single field with the given index.
+// When the field is always skipped, returns ok = false.
+func compileField(field reflect.StructField, index int, config planConfig) (fp fieldPlan, ok bool) {
+	fp = fieldPlan{
+		index: index,
+		field: field,
+		zero:  reflect.Zero(field.Type),
+	}
+
+	// fields explicitly ignored using "-" are always skipped
+	if field.Tag.Get(config.NameTag) == "-" || field.Tag.Get(config.ParserTag) == "-" {
+		return fp, false
+	}
+
+	// determine the type of parser to run
+	// using the default type when necessary
+	// types that parse themselves take precedence over the default.
+	//
+	// fields without any parser are kept, as a type parser may be registered for them.
+	fp.parser = field.Tag.Get(config.ParserTag)
+	fp.tagged = fp.parser != ""
+	if fp.parser == "" {
+		fp.parser = selfParserOf(field.Type)
+		fp.self = fp.parser != ""
+	}
+	if fp.parser == "" {
+		fp.parser = config.DefaultParser
+	}
+
+	// split the parser into the stages of a pipeline, and their arguments.
+	// a malformed parser reference is kept as is, and reported when it is used.
+	if stages, err := splitPipeline(fp.parser); err == nil {
+		fp.stages = stages
+		fp.parser = pipelineName(stages)
+	} else {
+		fp.parserErr = err
+	}
+
+	// type parsers only apply to fields without a parser tag
+	if !fp.tagged {
+		fp.typeStages = []parserStage{{name: field.Type.String()}}
+	}
+
+	// read the name and options from the name tag
+	var options []string
+	var noinline bool
+	fp.source, options = splitNameTag(field.Tag.Get(config.NameTag))
+	for _, option := range options {
+		switch option {
+		case "required":
+			fp.required = true
+		case "noinline":
+			noinline = true
+		}
+	}
+
+	// determine the sub-source to read from, if any
+	if config.SourceTag != "" {
+		fp.sub = field.Tag.Get(config.SourceTag)
+	}
+
+	// embedded structs are inlined automatically, like encoding/json does.
+	// a parser tag, a name or the "noinline" option opt out of this.
+	if field.Anonymous && !fp.tagged && !fp.self && fp.source == "" && !noinline {
+		switch {
+		case field.Type.Kind() == reflect.Struct:
+			fp.embedded = true
+		case field.Type.Kind() == reflect.Ptr && field.Type.Elem().Kind() == reflect.Struct && field.PkgPath == "":
+			// an unexported embedded pointer can not be allocated
+			fp.embedded = true
+		}
+	}
+
+	// unexported fields can not be set, with the exception of the fields of embedded structs.
+	if field.PkgPath != "" && !fp.embedded {
+		return fp, false
+	}
+
+	if fp.embedded {
+		fp.parser = config.InlineParser
+		if fp.parser == "" {
+			fp.parser = EmbeddedParser
+		}
+		fp.stages, fp.parserErr = nil, nil
+	}
+
+	// check if the inline parser is being requested.
+	if fp.embedded || (config.InlineParser != "" && !fp.self && fp.parser == config.InlineParser) {
+		fp.inline = true
+		if config.PrefixTag != "" {
+			fp.prefix = field.Tag.Get(config.PrefixTag)
+		}
+
+		switch field.Type.Kind() {
+		case reflect.Struct:
+		case reflect.Ptr:
+			fp.inlinePtr = true
+			fp.inlineErr = field.Type.Elem().Kind() != reflect.Struct
+		default:
+			fp.inlineErr = true
+		}
+
+		fp.source = ""
+		return fp, true
+	}
+
+	// determine which field to look at from the source
+	// use default when needed
+	if fp.source == "" {
+		if config.StrictNameTag {
+			return fp, false
+		}
+		fp.source = field.Name
+		fp.mapped = true
+	}
+
+	// determine the default value
+	if config.DefaultTag != "" {
+		fp.def, fp.hasDef = field.Tag.Lookup(config.DefaultTag)
+	}
+
+	// determine the validation rules
+	if config.ValidateTag != "" {
+		fp.rules = parseValidateTag(field.Tag.Get(config.ValidateTag))
+	}
+
+	return fp, true
+}
+
+// appendPath returns a new path consisting of path followed by name.
+// The underlying array of path is never modified.
+func appendPath(path []string, name string) []string {
+	return append(path[:len(path):len(path)], name)
+}
+
+// innerPath returns the path of the fields nested within the inlined field fp.
+// Embedded structs do not add to the path, as their fields are promoted.
+//
+// The path is only used by m.NameMapper; when it is nil, no path is built.
+func (m Marshal) innerPath(fp *fieldPlan, path []string) []string {
+	if fp.embedded || m.NameMapper == nil {
+		return path
+	}
+	return appendPath(path, fp.field.Name)
+}
+
+// embedding records which fields of embedded structs are hidden by other fields.
+// It mirrors the tree of embedded structs below the outermost struct of a plan, see computeEmbedding.
+// A nil *embedding hides no fields.
+type embedding struct {
+	hidden   []bool       // hidden[i] is true when the i-th field of the plan is hidden
+	embedded []*embedding // embedded[i] is the embedding of the i-th field, when it is an embedded struct
+}
+
+// isHidden checks if the i-th field of the plan is hidden.
+func (e *embedding) isHidden(i int) bool {
+	return e != nil && e.hidden[i]
+}
+
+// child returns the embedding for the struct nested within the i-th field of the plan.
+// When the field is not an embedded struct, returns nil.
+// The nested struct then uses the embedding of its own plan.
+func (e *embedding) child(i int) *embedding {
+	if e == nil {
+		return nil
+	}
+	return e.embedded[i]
+}
+
+// computeEmbedding computes the embedding of the struct typ with the given fields, following the rules of encoding/json.
+// When typ does not have any embedded structs, returns nil.
+//
+// The fields of typ and of all embedded structs below it compete for the keys they read.
+// Keys include prefixes, but are compared before Marshal.NameMapper is applied.
+// Out of the fields reading the same key, the field with the shallowest depth of embedding is used.
+// When there are multiple such fields, a field that has a name in the name tag is used.
+// When there is no unique such field either, all fields reading the key are hidden.
+// Fields of typ itself are never hidden, even when they read the same key.
+//
+// Fields inlined using the inline parser and fields reading from a sub-source start a new embedding of their own.
+// An embedded pointer to a struct that is already being embedded is hidden, to prevent infinite recursion.
+func computeEmbedding(typ reflect.Type, fields []fieldPlan, config planConfig) *embedding {
+	hasEmbedded := false
+	for i := range fields {
+		hasEmbedded = hasEmbedded || fields[i].embedded
+	}
+	if !hasEmbedded {
+		return nil
+	}
+
+	type candidate struct {
+		node   *embedding
+		index  int
+		depth  int
+		tagged bool
+	}
+	candidates := make(map[string][]candidate)
+
+	newNode := func(n int) *embedding {
+		return &embedding{hidden: make([]bool, n), embedded: make([]*embedding, n)}
+	}
+
+	active := map[reflect.Type]bool{typ: true}
+
+	var walk func(node *embedding, fields []fieldPlan, prefix string, depth int)
+	walk = func(node *embedding, fields []fieldPlan, prefix string, depth int) {
+		for i := range fields {
+			fp := &fields[i]
+			switch {
+			case fp.sub != "":
+			case fp.embedded:
+				eType := fp.field.Type
+				if fp.inlinePtr {
+					eType = eType.Elem()
+				}
+				if active[eType] {
+					node.hidden[i] = true
+					continue
+				}
+
+				eFields := compileFields(eType, config)
+				node.embedded[i] = newNode(len(eFields))
+
+				active[eType] = true
+				walk(node.embedded[i], eFields, prefix+fp.prefix, depth+1)
+				delete(active, eType)
+			case fp.inline:
+			default:
+				key := prefix + fp.source
+				candidates[key] = append(candidates[key], candidate{node: node, index: i, depth: depth, tagged: !fp.mapped})
+			}
+		}
+	}
+
+	root := newNode(len(fields))
+	walk(root, fields, "", 0)
+
+	for _, cs := range candidates {
+		if len(cs) < 2 {
+			continue
+		}
+
+		// find the shallowest depth
+		depth := cs[0].depth
+		for _, c := range cs {
+			if c.depth < depth {
+				depth = c.depth
+			}
+		}
+
+		// find the dominant field, if any.
+		// at depth 0, all fields are dominant.
+		dominant := -1
+		if depth > 0 {
+			var shallow, tagged []int
+			for i, c := range cs {
+				if c.depth != depth {
+					continue
+				}
+				shallow = append(shallow, i)
+				if c.tagged {
+					tagged = append(tagged, i)
+				}
+			}
+			switch {
+			case len(shallow) == 1:
+				dominant = shallow[0]
+			case len(tagged) == 1:
+				dominant = tagged[0]
+			}
+		}
+
+		for i, c := range cs {
+			if c.depth > depth || (depth > 0 && i != dominant) {
+				c.node.hidden[c.index] = true
+			}
+		}
+	}
+
+	return root
+}
+
+// splitNameTag splits the value of a name tag into a name and options.
+// Options are separated from the name and each other by commas, e.g. "name,required".
+func splitNameTag(tag string) (name string, options []string) {
+	parts := strings.Split(tag, ",")
+	return parts[0], parts[1:]
+}
+
+// parserStage is a single stage of a parser pipeline.
+type parserStage struct {
+	name string
+	args map[string]string
+}
+
+// splitPipeline splits a parser reference into the stages of a pipeline.
+// Stages are separated by "|", e.g. "trim|lower|int(base=16)".
+// Each stage is a parser reference with optional arguments, see splitParserArgs.
+// When ref is empty, returns no stages.
+func splitPipeline(ref string) ([]parserStage, error) {
+	if ref == "" {
+		return nil, nil
+	}
+
+	var stages []parserStage
+	add := func(text string) error {
+		if text == "" {
+			return fmt.Errorf("empty stage in parser pipeline %q", ref)
+		}
+		name, args, err := splitParserArgs(text)
+		if err != nil {
+			return err
+		}
+		stages = append(stages, parserStage{name: name, args: args})
+		return nil
+	}
+
+	// split at every "|" that is not part of an argument
+	start, depth := 0, 0
+	for i := 0; i < len(ref); i++ {
+		switch ref[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case '|':
+			if depth > 0 {
+				continue
+			}
+			if err := add(ref[start:i]); err != nil {
+				return nil, err
+			}
+			start = i + 1
+		}
+	}
+	if err := add(ref[start:]); err != nil {
+		return nil, err
+	}
+	return stages, nil
+}
+
+// pipelineName returns the name of a pipeline consisting of stages, without any arguments.
+func pipelineName(stages []parserStage) string {
+	names := make([]string, len(stages))
+	for i, stage := range stages {
+		names[i] = stage.name
+	}
+	return strings.Join(names, "|")
+}
+
+// splitParserArgs splits a parser reference into the name of the parser and its arguments.
+//
+// A parser reference is either a plain name, e.g. "int", or a name followed by a parenthesized list of arguments, e.g. "int(base=16,bits=8)".
+// Arguments are of the form "key=value", and are separated by commas.
+// Within a value, a backslash escapes the following character, e.g. "split(sep=\,)".
+// When there are no arguments, args is nil.
+func splitParserArgs(ref string) (name string, args map[string]string, err error) {
+	open := strings.IndexByte(ref, '(')
+	if open < 0 {
+		if strings.IndexByte(ref, ')') >= 0 {
+			return "", nil, fmt.Errorf("unexpected \")\" in parser %q", ref)
+		}
+		return ref, nil, nil
+	}
+	name = ref[:open]
+	if name == "" {
+		return "", nil, fmt.Errorf("missing parser name before \"(\" in %q", ref)
+	}
+
+	args = make(map[string]string)
+
+	var current strings.Builder
+	addArg := func() error {
+		arg := current.String()
+		current.Reset()
+
+		eq := strings.IndexByte(arg, '=')
+		switch {
+		case eq < 0:
+			return fmt.Errorf("argument %q of parser %q is missing \"=\"", arg, name)
+		case eq == 0:
+			return fmt.Errorf("argument %q of parser %q has an empty name", arg, name)
+		}
+
+		key, value := arg[:eq], arg[eq+1:]
+		if _, ok := args[key]; ok {
+			return fmt.Errorf("duplicate argument %q of parser %q", key, name)
+		}
+		args[key] = value
+		return nil
+	}
+
+	rest := ref[open+1:]
+	for i := 0; i < len(rest); i++ {
+		switch c := rest[i]; c {
+		case '\\':
+			if i+1 >= len(rest) {
+				return "", nil, fmt.Errorf("unterminated escape sequence in arguments of parser %q", name)
+			}
+			i++
+			current.WriteByte(rest[i])
+		case ',':
+			if err := addArg(); err != nil {
+				return "", nil, err
+			}
+		case ')':
+			if i != len(rest)-1 {
+				return "", nil, fmt.Errorf("unexpected %q after arguments of parser %q", rest[i+1:], name)
+			}
+			// "name()" has no arguments
+			if current.Len() == 0 && len(args) == 0 && i == 0 {
+				return name, nil, nil
+			}
+			if err := addArg(); err != nil {
+				return "", nil, err
+			}
+			return name, args, nil
+		case '(':
+			return "", nil, fmt.Errorf("unexpected \"(\" in arguments of parser %q", name)
+		default:
+			current.WriteByte(c)
+		}
+	}
+	return "", nil, fmt.Errorf("missing \")\" at end of arguments of parser %q", name)
+}
@@ -0,0 +1,230 @@
+package stringreader
+
+import (
+	"reflect"
+	"testing"
+)
+
+type benchmarkNested struct {
+	Host string `read:"host"`
+	Port string `read:"port"`
+}
+
+type benchmarkStruct struct {
+	User     string           `read:"user"`
+	Password string           `read:"password"`
+	Database string           `read:"database"`
+	Primary  benchmarkNested  `type:"inline"`
+	Fallback *benchmarkNested `type:"inline"`
+	Ignored  string           `type:"none"`
+}
+
+func benchmarkUnmarshal(b *testing.B) {
+	m := Marshal{
+		NameTag:       "read",
+		ParserTag:     "type",
+		DefaultParser: "string",
+		InlineParser:  "inline",
+	}
+	m.RegisterSingleParser("string", func(value string, ok bool, ctx UnmarshalContext) (interface{}, error) {
+		return value, nil
+	})
+	m.RegisterSingleParser("none", func(value string, ok bool, ctx UnmarshalContext) (interface{}, error) {
+		return nil, nil
+	})
+
+	source := SourceSingleMap{
+		"user":     "user",
+		"password": "password",
+		"database": "database",
+		"host":     "localhost",
+		"port":     "5432",
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var dest benchmarkStruct
+		if err := m.UnmarshalSingle(&dest, source); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkMarshal_Unmarshal compares cached plans against plans that are compiled on every call.
+//
+// The latter reads all tags and resolves all parsers for every call, like unmarshaling did before plans were cached.
+// It is not that original code path however, as compiling a plan does additional work, e.g. to find conflicts between embedded fields.
+func BenchmarkMarshal_Unmarshal(b *testing.B) {
+	b.Run("cached", benchmarkUnmarshal)
+	b.Run("compiled per call", func(b *testing.B) {
+		defer func(old func(reflect.Type, planConfig) *plan) { loadPlan = old }(loadPlan)
+		loadPlan = compilePlan
+
+		benchmarkUnmarshal(b)
+	})
+}
+
+func TestCachedPlan(t *testing.T) {
+	config := planConfig{NameTag: "read", ParserTag: "type", InlineParser: "inline"}
+	typ := reflect.TypeOf(benchmarkStruct{})
+
+	first := cachedPlan(typ, config)
+	if second := cachedPlan(typ, config); first != second {
+		t.Error("cachedPlan() returned different plans for identical keys")
+	}
+
+	config.DefaultParser = "string"
+	third := cachedPlan(typ, config)
+	if third == first {
+		t.Error("cachedPlan() returned identical plans for different configs")
+	}
+
+	// without a default parser, only fields with an explicit parser have a parser.
+	// the remaining fields are kept, as a type parser may apply to them.
+	if got, want := countParsers(first), 3; got != want {
+		t.Errorf("cachedPlan() has %d fields with a parser, want = %d", got, want)
+	}
+	if got, want := countParsers(third), 6; got != want {
+		t.Errorf("cachedPlan() has %d fields with a parser, want = %d", got, want)
+	}
+}
+
+func TestMarshal_resolvedParsers(t *testing.T) {
+	m := Marshal{ParserTag: "type"}
+	m.RegisterSingleParser("value", func(value string, ok bool, ctx UnmarshalContext) (interface{}, error) {
+		return "first", nil
+	})
+
+	var dest struct {
+		Value string `type:"value"`
+	}
+	unmarshal := func() string {
+		if err := m.UnmarshalSingle(&dest, SourceSingleMap{"Value": "value"}); err != nil {
+			t.Fatalf("Marshal.UnmarshalSingle() err = %v", err)
+		}
+		return dest.Value
+	}
+
+	if got := unmarshal(); got != "first" {
+		t.Errorf("Marshal.UnmarshalSingle() = %q, want = %q", got, "first")
+	}
+
+	// registering a parser invalidates the cached parsers
+	m.RegisterSingleParser("value", func(value string, ok bool, ctx UnmarshalContext) (interface{}, error) {
+		return "second", nil
+	})
+	if got := unmarshal(); got != "second" {
+		t.Errorf("Marshal.UnmarshalSingle() after RegisterSingleParser = %q, want = %q", got, "second")
+	}
+
+	// so does replacing the map of parsers
+	m.SingleParsers = map[string]SingleParser{
+		"value": func(value string, ok bool, ctx UnmarshalContext) (interface{}, error) {
+			return "third", nil
+		},
+	}
+	if got := unmarshal(); got != "third" {
+		t.Errorf("Marshal.UnmarshalSingle() after replacing SingleParsers = %q, want = %q", got, "third")
+	}
+
+	// an unchanged marshal uses the cached parsers
+	p := loadPlan(reflect.TypeOf(dest), m.planConfig())
+	if first, second := m.resolvedParsers(p), m.resolvedParsers(p); first != second {
+		t.Error("Marshal.resolvedParsers() resolved unchanged parsers again")
+	}
+}
+
+// countParsers counts the fields of p that have a named parser.
+func countParsers(p *plan) (count int) {
+	for _, fp := range p.fields {
+		if fp.parser != "" {
+			count++
+		}
+	}
+	return count
+}
+
+func Test_splitParserArgs(t *testing.T) {
+	tests := []struct {
+		ref      string
+		wantName string
+		wantArgs map[string]string
+		wantErr  string
+	}{
+		{"int", "int", nil, ""},
+		{"int()", "int", nil, ""},
+		{"int(base=16,bits=8)", "int", map[string]string{"base": "16", "bits": "8"}, ""},
+		{"split(sep=;)", "split", map[string]string{"sep": ";"}, ""},
+		{`split(sep=\,)`, "split", map[string]string{"sep": ","}, ""},
+		{`split(sep=\))`, "split", map[string]string{"sep": ")"}, ""},
+		{"split(sep=)", "split", map[string]string{"sep": ""}, ""},
+
+		{"int(base=16", "", nil, `missing ")" at end of arguments of parser "int"`},
+		{"int(base)", "", nil, `argument "base" of parser "int" is missing "="`},
+		{"int(=16)", "", nil, `argument "=16" of parser "int" has an empty name`},
+		{"int(base=16,base=8)", "", nil, `duplicate argument "base" of parser "int"`},
+		{"int(base=16)x", "", nil, `unexpected "x" after arguments of parser "int"`},
+		{"int(base=(16))", "", nil, `unexpected "(" in arguments of parser "int"`},
+		{"int)", "", nil, `unexpected ")" in parser "int)"`},
+		{"(base=16)", "", nil, `missing parser name before "(" in "(base=16)"`},
+		{`split(sep=\`, "", nil, `unterminated escape sequence in arguments of parser "split"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			gotName, gotArgs, err := splitParserArgs(tt.ref)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("splitParserArgs() err = %v, want = %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("splitParserArgs() err = %v", err)
+			}
+			if gotName != tt.wantName || !reflect.DeepEqual(gotArgs, tt.wantArgs) {
+				t.Errorf("splitParserArgs() = %q, %v, want = %q, %v", gotName, gotArgs, tt.wantName, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func Test_splitPipeline(t *testing.T) {
+	tests := []struct {
+		ref        string
+		wantStages []parserStage
+		wantName   string
+		wantErr    string
+	}{
+		{"", nil, "", ""},
+		{"int", []parserStage{{"int", nil}}, "int", ""},
+		{"trim|lower|int(base=16)", []parserStage{{"trim", nil}, {"lower", nil}, {"int", map[string]string{"base": "16"}}}, "trim|lower|int", ""},
+		{"split(sep=|)|json", []parserStage{{"split", map[string]string{"sep": "|"}}, {"json", nil}}, "split|json", ""},
+		{`split(sep=\))|json`, []parserStage{{"split", map[string]string{"sep": ")"}}, {"json", nil}}, "split|json", ""},
+
+		{"trim||lower", nil, "", `empty stage in parser pipeline "trim||lower"`},
+		{"trim|", nil, "", `empty stage in parser pipeline "trim|"`},
+		{"trim|int(base=16", nil, "", `missing ")" at end of arguments of parser "int"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			gotStages, err := splitPipeline(tt.ref)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("splitPipeline() err = %v, want = %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("splitPipeline() err = %v", err)
+			}
+			if !reflect.DeepEqual(gotStages, tt.wantStages) {
+				t.Errorf("splitPipeline() = %v, want = %v", gotStages, tt.wantStages)
+			}
+			if gotName := pipelineName(gotStages); gotName != tt.wantName {
+				t.Errorf("pipelineName() = %q, want = %q", gotName, tt.wantName)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package stringreader
+
+import (
+	"database/sql"
+	"encoding"
+	"flag"
+	"fmt"
+	"reflect"
+)
+
+// Names reported by UnmarshalContext.Parser for fields that parse themselves.
+// See Marshal.UnmarshalState.
+const (
+	TextUnmarshalerParser = "encoding.TextUnmarshaler"
+	FlagValueParser       = "flag.Value"
+	SQLScannerParser      = "sql.Scanner"
+)
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	flagValueType       = reflect.TypeOf((*flag.Value)(nil)).Elem()
+	sqlScannerType      = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+)
+
+// selfParserOf returns the name of the method-based parser to use for fields of type typ.
+// When typ does not know how to parse itself, returns the empty string.
+//
+// A type parses itself if either a pointer to it, or the type itself when it is a pointer,
+// implements encoding.TextUnmarshaler, flag.Value or sql.Scanner, checked in that order.
+func selfParserOf(typ reflect.Type) string {
+	implements := func(iface reflect.Type) bool {
+		return reflect.PtrTo(typ).Implements(iface) || (typ.Kind() == reflect.Ptr && typ.Implements(iface))
+	}
+
+	switch {
+	case implements(textUnmarshalerType):
+		return TextUnmarshalerParser
+	case implements(flagValueType):
+		return FlagValueParser
+	case implements(sqlScannerType):
+		return SQLScannerParser
+	default:
+		return ""
+	}
+}
+
+// selfParsers holds the parsers for fields that parse themselves, keyed by the name returned from selfParserOf.
+var selfParsers = map[string]SingleParser{
+	TextUnmarshalerParser: parseSelf(func(target interface{}, value string) error {
+		return target.(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}),
+	FlagValueParser: parseSelf(func(target interface{}, value string) error {
+		return target.(flag.Value).Set(value)
+	}),
+	SQLScannerParser: parseSelf(func(target interface{}, value string) error {
+		return target.(sql.Scanner).Scan(value)
+	}),
+}
+
+// parseSelf returns a SingleParser that creates a new value of the destination type, and then calls parse on it.
+// Target is a non-nil pointer to the new value; for pointer destinations the pointer itself.
+func parseSelf(parse func(target interface{}, value string) error) SingleParser {
+	return func(value string, ok bool, ctx UnmarshalContext) (interface{}, error) {
+		typ := ctx.Type()
+
+		// a pointer type never implements the interfaces through a pointer to itself.
+		// so for pointers, allocate the element type instead.
+		var target, result reflect.Value
+		if typ.Kind() == reflect.Ptr {
+			target = reflect.New(typ.Elem())
+			result = target
+		} else {
+			target = reflect.New(typ)
+			result = target.Elem()
+		}
+
+		if err := parse(target.Interface(), value); err != nil {
+			return nil, err
+		}
+		return result.Interface(), nil
+	}
+}
+
+// formatSelf formats fields that parse themselves.
+// It uses encoding.TextMarshaler when implemented, and fmt.Stringer otherwise.
+// Nil pointers are omitted.
+func formatSelf(value interface{}, ctx UnmarshalContext) (string, bool, error) {
+	rValue := reflect.ValueOf(value)
+	if rValue.Kind() == reflect.Ptr && rValue.IsNil() {
+		return "", false, nil
+	}
+
+	// prefer the pointer receiver, so that both kinds of methods are found.
+	if rValue.Kind() != reflect.Ptr {
+		ptr := reflect.New(rValue.Type())
+		ptr.Elem().Set(rValue)
+		value = ptr.Interface()
+	}
+
+	switch v := value.(type) {
+	case encoding.TextMarshaler:
+		text, err := v.MarshalText()
+		if err != nil {
+			return "", false, err
+		}
+		return string(text), true, nil
+	case fmt.Stringer:
+		return v.String(), true, nil
+	}
+	return "", false, fmt.Errorf("type %T implements neither encoding.TextMarshaler nor fmt.Stringer", value)
+}
@@ -0,0 +1,127 @@
+package stringreader_test
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tkw1536/stringreader"
+)
+
+// Level is a custom enum that implements flag.Value.
+type Level int
+
+func (l *Level) Set(value string) error {
+	switch strings.ToLower(value) {
+	case "debug":
+		*l = 0
+	case "info":
+		*l = 1
+	default:
+		return fmt.Errorf("unknown level %q", value)
+	}
+	return nil
+}
+
+func (l Level) String() string {
+	if l == 0 {
+		return "debug"
+	}
+	return "info"
+}
+
+func ExampleMarshal_UnmarshalSingle_self() {
+	var m stringreader.Marshal
+	m.ParserTag = "type"
+	m.RegisterStandardParsers()
+
+	var config struct {
+		Addr    net.IP
+		Started time.Time
+		Big     *big.Int
+		Level   Level
+		Comment sql.NullString
+		Port    int
+	}
+
+	err := m.UnmarshalSingle(&config, stringreader.SourceSingleMap{
+		"Addr":    "127.0.0.1",
+		"Started": "2021-01-02T15:04:05Z",
+		"Big":     "123456789012345678901234567890",
+		"Level":   "INFO",
+		"Comment": "hello",
+		"Port":    "8080",
+	})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(config.Addr, config.Started.Year(), config.Big, config.Level, config.Comment.String, config.Port)
+
+	// Output:
+	// 127.0.0.1 2021 123456789012345678901234567890 info hello 8080
+}
+
+func TestMarshal_Unmarshal_self(t *testing.T) {
+	var m stringreader.Marshal
+	m.ParserTag = "type"
+
+	type Config struct {
+		Addr  net.IP
+		Level Level
+		Name  string // skipped, there is no default parser
+	}
+
+	t.Run("missing key", func(t *testing.T) {
+		config := Config{Addr: net.IPv4(1, 2, 3, 4), Level: 1}
+		if err := m.UnmarshalSingle(&config, stringreader.SourceSingleMap{}); err != nil {
+			t.Fatalf("Marshal.UnmarshalSingle() err = %v", err)
+		}
+		if !config.Addr.Equal(net.IPv4(1, 2, 3, 4)) || config.Level != 1 {
+			t.Errorf("Marshal.UnmarshalSingle() changed fields with missing keys: %+v", config)
+		}
+	})
+
+	t.Run("parse error", func(t *testing.T) {
+		var config Config
+		err := m.UnmarshalSingle(&config, stringreader.SourceSingleMap{"Addr": "not-an-ip"})
+
+		var fErr stringreader.ErrFailedToParseField
+		if !errors.As(err, &fErr) {
+			t.Fatalf("Marshal.UnmarshalSingle() err = %v, want ErrFailedToParseField", err)
+		}
+		if fErr.Parser() != stringreader.TextUnmarshalerParser {
+			t.Errorf("Marshal.UnmarshalSingle() err.Parser() = %q, want = %q", fErr.Parser(), stringreader.TextUnmarshalerParser)
+		}
+	})
+
+	t.Run("explicit tag wins", func(t *testing.T) {
+		var m2 stringreader.Marshal
+		m2.ParserTag = "type"
+		m2.RegisterStandardParsers()
+
+		var config struct {
+			Level Level `type:"int"`
+		}
+		if err := m2.UnmarshalSingle(&config, stringreader.SourceSingleMap{"Level": "1"}); err != nil {
+			t.Fatalf("Marshal.UnmarshalSingle() err = %v", err)
+		}
+		if config.Level != 1 {
+			t.Errorf("Marshal.UnmarshalSingle() Level = %v, want = 1", config.Level)
+		}
+	})
+
+	t.Run("marshal", func(t *testing.T) {
+		single, _, err := m.Marshal(Config{Addr: net.IPv4(10, 0, 0, 1), Level: 1})
+		if err != nil {
+			t.Fatalf("Marshal.Marshal() err = %v", err)
+		}
+		if single["Addr"] != "10.0.0.1" || single["Level"] != "info" {
+			t.Errorf("Marshal.Marshal() = %v", single)
+		}
+	})
+}
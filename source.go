@@ -1,5 +1,7 @@
 package stringreader
 
+import "context"
+
 // Source represents a source of string-identified data.
 // Each datum is identified using a string key.
 //
@@ -116,3 +118,126 @@ func (s SourceSmartSplit) LookupAll(value string) ([]string, bool) {
 		return nil, false
 	}
 }
+
+// SourceSelector is a Source that contains named sub-sources.
+// Fields select a sub-source using Marshal.SourceTag.
+type SourceSelector interface {
+	Source
+
+	// Select returns the sub-source with the provided name.
+	// When no such sub-source exists, returns nil and false.
+	Select(name string) (Source, bool)
+}
+
+// SourceContext is a Source that supports cancellation of lookups.
+// When used with Marshal.UnmarshalContextual, the context-aware methods are used instead of Lookup and LookupAll.
+type SourceContext interface {
+	Source
+
+	// LookupContext is like Lookup, but may be canceled using ctx.
+	// When ctx is done, it should return promptly.
+	LookupContext(ctx context.Context, key string) (value string, ok bool)
+
+	// LookupAllContext is like LookupAll, but may be canceled using ctx.
+	// When ctx is done, it should return promptly.
+	LookupAllContext(ctx context.Context, key string) (value []string, ok bool)
+}
+
+// lookupContext looks up key in source, using LookupContext when source implements SourceContext.
+func lookupContext(ctx context.Context, source Source, key string) (string, bool) {
+	if cs, ok := source.(SourceContext); ok {
+		return cs.LookupContext(ctx, key)
+	}
+	return source.Lookup(key)
+}
+
+// lookupAllContext looks up key in source, using LookupAllContext when source implements SourceContext.
+func lookupAllContext(ctx context.Context, source Source, key string) ([]string, bool) {
+	if cs, ok := source.(SourceContext); ok {
+		return cs.LookupAllContext(ctx, key)
+	}
+	return source.LookupAll(key)
+}
+
+// SourceSet implements SourceSelector using a map of named sources.
+//
+// Lookups that do not select a sub-source are answered by Default.
+// When Default is nil, simulates an empty source.
+type SourceSet struct {
+	Default Source
+	Sources map[string]Source
+}
+
+func (s SourceSet) Lookup(key string) (string, bool) {
+	if s.Default == nil {
+		return "", false
+	}
+	return s.Default.Lookup(key)
+}
+
+func (s SourceSet) LookupAll(key string) ([]string, bool) {
+	if s.Default == nil {
+		return nil, false
+	}
+	return s.Default.LookupAll(key)
+}
+
+func (s SourceSet) Select(name string) (Source, bool) {
+	source, ok := s.Sources[name]
+	if !ok || source == nil {
+		return nil, false
+	}
+	return source, true
+}
+
+// SourcePrefix returns a view of source in which every key is prefixed with prefix before it is looked up.
+//
+// The returned source implements SourcePositioner, SourceSelector and SourceContext.
+// Positions are forwarded to source, if it implements SourcePositioner.
+// Context-aware lookups are forwarded to source, if it implements SourceContext.
+// Sub-sources are selected from source, if it implements SourceSelector, and are then prefixed in the same way.
+func SourcePrefix(source Source, prefix string) Source {
+	return sourcePrefix{Source: source, prefix: prefix}
+}
+
+// sourcePrefix implements SourcePrefix.
+type sourcePrefix struct {
+	Source
+	prefix string
+}
+
+func (s sourcePrefix) Lookup(key string) (string, bool) {
+	return s.Source.Lookup(s.prefix + key)
+}
+
+func (s sourcePrefix) LookupAll(key string) ([]string, bool) {
+	return s.Source.LookupAll(s.prefix + key)
+}
+
+func (s sourcePrefix) LookupContext(ctx context.Context, key string) (string, bool) {
+	return lookupContext(ctx, s.Source, s.prefix+key)
+}
+
+func (s sourcePrefix) LookupAllContext(ctx context.Context, key string) ([]string, bool) {
+	return lookupAllContext(ctx, s.Source, s.prefix+key)
+}
+
+func (s sourcePrefix) Position(key string) (Position, bool) {
+	positioner, ok := s.Source.(SourcePositioner)
+	if !ok {
+		return Position{}, false
+	}
+	return positioner.Position(s.prefix + key)
+}
+
+func (s sourcePrefix) Select(name string) (Source, bool) {
+	selector, ok := s.Source.(SourceSelector)
+	if !ok {
+		return nil, false
+	}
+	source, ok := selector.Select(name)
+	if !ok {
+		return nil, false
+	}
+	return sourcePrefix{Source: source, prefix: s.prefix}, true
+}
@@ -0,0 +1,267 @@
+package stringreader
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// SourceArgs implements Source using command line arguments.
+// See ParseArgs on how to create a SourceArgs.
+//
+// Lookup returns the last value of a flag, LookupAll returns all values in order.
+type SourceArgs struct {
+	Flags map[string][]string // values of each flag
+	Args  []string            // positional arguments
+}
+
+func (s SourceArgs) Lookup(key string) (string, bool) {
+	values, ok := s.Flags[key]
+	if !ok || len(values) == 0 {
+		return "", false
+	}
+	return values[len(values)-1], true
+}
+
+func (s SourceArgs) LookupAll(key string) ([]string, bool) {
+	values, ok := s.Flags[key]
+	return values, ok
+}
+
+// ParseArgs parses POSIX and GNU style command line arguments into a SourceArgs.
+// Args should not contain the program name, e.g. os.Args[1:].
+//
+// Flags are named without leading dashes, and may be given in one of the following forms:
+//
+//   - "--name=value" or "-n=value"
+//   - "--name value" or "-n value"
+//   - "-nvalue", when n is a single character
+//   - "--name" or "-n", when name is contained in bools; this sets the value "true"
+//   - "--no-name", when name is contained in bools; this sets the value "false"
+//   - "-abc", when a, b and c are contained in bools; this is equivalent to "-a -b -c"
+//
+// Flags may be repeated, each occurrence adds a value.
+// Any other argument is a positional argument.
+// All arguments after "--" are positional arguments.
+//
+// When a flag is missing a name or a value, an error is returned.
+func ParseArgs(args []string, bools ...string) (SourceArgs, error) {
+	isBool := make(map[string]bool, len(bools))
+	for _, name := range bools {
+		isBool[name] = true
+	}
+
+	source := SourceArgs{Flags: make(map[string][]string)}
+	add := func(name, value string) {
+		source.Flags[name] = append(source.Flags[name], value)
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		switch {
+		// all remaining arguments are positional
+		case arg == "--":
+			source.Args = append(source.Args, args[i+1:]...)
+			return source, nil
+
+		// positional argument
+		case arg == "-" || !strings.HasPrefix(arg, "-"):
+			source.Args = append(source.Args, arg)
+
+		// long flag
+		case strings.HasPrefix(arg, "--"):
+			name := arg[2:]
+			if eq := strings.IndexByte(name, '='); eq >= 0 {
+				if eq == 0 {
+					return SourceArgs{}, fmt.Errorf("ParseArgs: flag %q is missing a name", arg)
+				}
+				add(name[:eq], name[eq+1:])
+				continue
+			}
+
+			if isBool[name] {
+				add(name, "true")
+				continue
+			}
+			if negated := strings.TrimPrefix(name, "no-"); negated != name && isBool[negated] {
+				add(negated, "false")
+				continue
+			}
+
+			if i+1 >= len(args) {
+				return SourceArgs{}, fmt.Errorf("ParseArgs: flag %q requires a value", arg)
+			}
+			i++
+			add(name, args[i])
+
+		// short flag(s)
+		default:
+			shorts := arg[1:]
+			if eq := strings.IndexByte(shorts, '='); eq >= 0 {
+				if eq == 0 {
+					return SourceArgs{}, fmt.Errorf("ParseArgs: flag %q is missing a name", arg)
+				}
+				add(shorts[:eq], shorts[eq+1:])
+				continue
+			}
+
+			// consume boolean flags, until a non-boolean flag is found.
+			for len(shorts) > 0 {
+				name := shorts[:1]
+				shorts = shorts[1:]
+
+				if isBool[name] {
+					add(name, "true")
+					continue
+				}
+
+				// the remainder of the argument is the value
+				if len(shorts) > 0 {
+					add(name, shorts)
+					break
+				}
+
+				if i+1 >= len(args) {
+					return SourceArgs{}, fmt.Errorf("ParseArgs: flag %q requires a value", "-"+name)
+				}
+				i++
+				add(name, args[i])
+			}
+		}
+	}
+
+	return source, nil
+}
+
+// FlagSet creates a new flag.FlagSet with a flag for every key that m reads when unmarshaling into dest.
+// Dest must be a pointer to a struct; if this is not the case, ErrDestIsNil or ErrNotPointerToStruct is returned.
+//
+// The usage of each flag lists the destination field and parser.
+// Fields of boolean type are defined as boolean flags.
+// Flags may be given multiple times; SourceFlagSet can be used to read the values after parsing.
+//
+// When a key can not be used as the name of a flag, because it starts with "-" or contains "=", an error is returned.
+func (m Marshal) FlagSet(dest interface{}, name string, errorHandling flag.ErrorHandling) (*flag.FlagSet, error) {
+	if dest == nil {
+		return nil, ErrDestIsNil
+	}
+
+	dType := reflect.TypeOf(dest)
+	if dType.Kind() != reflect.Ptr || dType.Elem().Kind() != reflect.Struct {
+		return nil, ErrNotPointerToStruct
+	}
+
+	fs := flag.NewFlagSet(name, errorHandling)
+
+	var err error
+	m.walkFields(dType.Elem(), func(key string, fp *fieldPlan) {
+		// the same key may be read by multiple fields
+		if err != nil || fs.Lookup(key) != nil {
+			return
+		}
+
+		// fields without a parser are never read
+		rp, _, _ := m.resolveParser(fp)
+		if rp.name == "" {
+			return
+		}
+
+		// flag.FlagSet.Var panics for these names
+		if strings.HasPrefix(key, "-") || strings.Contains(key, "=") {
+			err = fmt.Errorf("Marshal.FlagSet: key %q of field %q is not a valid flag name", key, fp.field.Name)
+			return
+		}
+
+		value := &flagValue{isBool: fp.field.Type.Kind() == reflect.Bool}
+		fs.Var(value, key, fmt.Sprintf("%s (%s)", fp.field.Name, rp.name))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
+// walkFields calls visit for every field that m reads from a source when unmarshaling into the struct type typ.
+// Key is the key that is read, including prefixes of inlined structs.
+// Inlined structs are visited recursively, but each struct type at most once along every path.
+func (m Marshal) walkFields(typ reflect.Type, visit func(key string, fp *fieldPlan)) {
+	m.walkFieldsRec(typ, nil, nil, "", visit, make(map[reflect.Type]bool))
+}
+
+func (m Marshal) walkFieldsRec(typ reflect.Type, path []string, shadow *embedding, prefix string, visit func(key string, fp *fieldPlan), active map[reflect.Type]bool) {
+	if active[typ] {
+		return
+	}
+	active[typ] = true
+	defer delete(active, typ)
+
+	plan := loadPlan(typ, m.planConfig())
+	if shadow == nil {
+		shadow = plan.embedding
+	}
+	for i := range plan.fields {
+		fp := &plan.fields[i]
+
+		// skip fields hidden by other fields of embedded structs
+		if shadow.isHidden(i) {
+			continue
+		}
+
+		switch {
+		case !fp.inline:
+			key := fp.source
+			if fp.mapped && m.NameMapper != nil {
+				key = m.NameMapper(fp.field, path)
+			}
+			visit(prefix+key, fp)
+		case fp.inlineErr:
+		case fp.inlinePtr:
+			m.walkFieldsRec(fp.field.Type.Elem(), m.innerPath(fp, path), shadow.child(i), prefix+fp.prefix, visit, active)
+		default:
+			m.walkFieldsRec(fp.field.Type, m.innerPath(fp, path), shadow.child(i), prefix+fp.prefix, visit, active)
+		}
+	}
+}
+
+// flagValue implements flag.Value by collecting all values passed to it.
+type flagValue struct {
+	isBool bool
+	values []string
+}
+
+func (v *flagValue) String() string {
+	if v == nil || len(v.values) == 0 {
+		return ""
+	}
+	return v.values[len(v.values)-1]
+}
+
+func (v *flagValue) Set(value string) error {
+	v.values = append(v.values, value)
+	return nil
+}
+
+func (v *flagValue) IsBoolFlag() bool {
+	return v.isBool
+}
+
+// SourceFlagSet returns a Source that reads the flags that have been set in fs.
+//
+// For flags created using Marshal.FlagSet, LookupAll returns every value that was passed.
+// For other flags, both Lookup and LookupAll return the result of the String method of the flag's value.
+func SourceFlagSet(fs *flag.FlagSet) SourceArgs {
+	source := SourceArgs{
+		Flags: make(map[string][]string),
+		Args:  fs.Args(),
+	}
+	fs.Visit(func(f *flag.Flag) {
+		if value, ok := f.Value.(*flagValue); ok {
+			source.Flags[f.Name] = append([]string(nil), value.values...)
+			return
+		}
+		source.Flags[f.Name] = []string{f.Value.String()}
+	})
+	return source
+}
@@ -0,0 +1,178 @@
+package stringreader_test
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/tkw1536/stringreader"
+)
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		bools     []string
+		wantFlags map[string][]string
+		wantArgs  []string
+		wantErr   bool
+	}{
+		{
+			name:      "long flags",
+			args:      []string{"--name=value", "--other", "value", "--name", "again"},
+			wantFlags: map[string][]string{"name": {"value", "again"}, "other": {"value"}},
+		},
+		{
+			name:      "short flags",
+			args:      []string{"-n=value", "-o", "value", "-pvalue"},
+			wantFlags: map[string][]string{"n": {"value"}, "o": {"value"}, "p": {"value"}},
+		},
+		{
+			name:      "boolean flags",
+			args:      []string{"--verbose", "--no-color", "-ab", "-axvalue"},
+			bools:     []string{"verbose", "color", "a", "b"},
+			wantFlags: map[string][]string{"verbose": {"true"}, "color": {"false"}, "a": {"true", "true"}, "b": {"true"}, "x": {"value"}},
+		},
+		{
+			name:      "positional arguments",
+			args:      []string{"first", "--name=value", "-", "second", "--", "--third"},
+			wantFlags: map[string][]string{"name": {"value"}},
+			wantArgs:  []string{"first", "-", "second", "--third"},
+		},
+		{
+			name:    "missing long value",
+			args:    []string{"--name"},
+			wantErr: true,
+		},
+		{
+			name:    "missing short value",
+			args:    []string{"-n"},
+			wantErr: true,
+		},
+		{
+			name:    "missing long name",
+			args:    []string{"--=value"},
+			wantErr: true,
+		},
+		{
+			name:    "missing short name",
+			args:    []string{"-=value"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := stringreader.ParseArgs(tt.args, tt.bools...)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("wantErr = true, err = nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseArgs() err = %s, want = nil", err)
+			}
+
+			if !reflect.DeepEqual(got.Flags, tt.wantFlags) {
+				t.Errorf("ParseArgs() flags = %v, want = %v", got.Flags, tt.wantFlags)
+			}
+			if !reflect.DeepEqual(got.Args, tt.wantArgs) {
+				t.Errorf("ParseArgs() args = %v, want = %v", got.Args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestMarshal_FlagSet_invalidKey(t *testing.T) {
+	var m stringreader.Marshal
+	m.NameTag = "flag"
+	m.RegisterStandardParsers()
+
+	for _, dest := range []interface{}{
+		&struct {
+			Field string `flag:"a=b"`
+		}{},
+		&struct {
+			Field string `flag:"-a"`
+		}{},
+	} {
+		fs, err := m.FlagSet(dest, "test", flag.ContinueOnError)
+		if err == nil || fs != nil {
+			t.Errorf("Marshal.FlagSet() = %v, %v, want an error", fs, err)
+		}
+	}
+}
+
+func ExampleParseArgs() {
+	var marshal stringreader.Marshal
+	marshal.NameTag = "flag"
+	marshal.RegisterStandardParsers()
+
+	type Options struct {
+		Host    string `flag:"host"`
+		Port    uint16 `flag:"port"`
+		Verbose bool   `flag:"verbose"`
+	}
+
+	source, err := stringreader.ParseArgs([]string{"--host", "localhost", "--port=8080", "--no-verbose", "input.txt"}, "verbose")
+	if err != nil {
+		panic(err)
+	}
+
+	var options Options
+	if err := marshal.Unmarshal(&options, source); err != nil {
+		panic(err)
+	}
+	fmt.Printf("%+v\n", options)
+	fmt.Println(source.Args)
+
+	// Output:
+	// {Host:localhost Port:8080 Verbose:false}
+	// [input.txt]
+}
+
+func ExampleMarshal_FlagSet() {
+	var marshal stringreader.Marshal
+	marshal.NameTag = "flag"
+	marshal.ParserTag = "parser"
+	marshal.InlineParser = "inline"
+	marshal.RegisterStandardParsers()
+
+	type Server struct {
+		Host string `flag:"host"`
+		Port uint16 `flag:"port"`
+	}
+
+	type Options struct {
+		Server  Server `parser:"inline"`
+		Verbose bool   `flag:"verbose"`
+	}
+
+	var options Options
+	fs, err := marshal.FlagSet(&options, "example", flag.ContinueOnError)
+	if err != nil {
+		panic(err)
+	}
+	fs.SetOutput(os.Stdout)
+	fs.PrintDefaults()
+
+	// parse some flags, and read them using the marshal
+	if err := fs.Parse([]string{"-host", "localhost", "-verbose"}); err != nil {
+		panic(err)
+	}
+	if err := marshal.Unmarshal(&options, stringreader.SourceFlagSet(fs)); err != nil {
+		panic(err)
+	}
+	fmt.Printf("%+v\n", options)
+
+	// Output:
+	//   -host value
+	//     	Host (auto)
+	//   -port value
+	//     	Port (auto)
+	//   -verbose
+	//     	Verbose (auto)
+	// {Server:{Host:localhost Port:0} Verbose:true}
+}
@@ -0,0 +1,150 @@
+package stringreader
+
+import (
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ParseDotEnv parses a dotenv file from r into a SourceFile.
+// Name is the name of the file, and is used for positions and errors only.
+//
+// Each line of the file is either blank, a comment starting with '#', or an assignment of the form "KEY=VALUE".
+// Assignments may be prefixed by "export".
+// Values may be unquoted, single-quoted or double-quoted:
+//
+//   - unquoted values are trimmed, and end at a '#' preceded by whitespace
+//   - single-quoted values are taken literally
+//   - double-quoted values may contain the escape sequences \n, \r, \t, \", \\ and \$
+//
+// Quoted values may span multiple lines.
+// Variables within values are not expanded.
+//
+// When the file contains a syntax error, an ErrSyntax is returned.
+func ParseDotEnv(name string, r io.Reader) (SourceFile, error) {
+	return parseFile(parseDotEnv, name, r)
+}
+
+// ReadDotEnv is like ParseDotEnv, but reads the file at path.
+func ReadDotEnv(path string) (SourceFile, error) {
+	return readFile(parseDotEnv, path)
+}
+
+func parseDotEnv(name string, content string) (source SourceFile, err error) {
+	lines := strings.Split(content, "\n")
+	for i := 0; i < len(lines); i++ {
+		pos := Position{File: name, Line: i + 1}
+
+		line := strings.TrimSpace(lines[i])
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		// remove an optional export
+		if rest := strings.TrimPrefix(line, "export"); rest != line && rest != "" && isSpace(rest[0]) {
+			line = strings.TrimSpace(rest)
+		}
+
+		// split into key and value
+		eq := strings.IndexByte(line, '=')
+		if eq < 0 {
+			return SourceFile{}, ErrSyntax{Position: pos, Message: "expected '='"}
+		}
+		key := strings.TrimSpace(line[:eq])
+		if !isDotEnvKey(key) {
+			return SourceFile{}, ErrSyntax{Position: pos, Message: "invalid variable name " + strconv.Quote(key)}
+		}
+		value := strings.TrimLeft(line[eq+1:], " \t")
+
+		// unquoted value
+		if value == "" || (value[0] != '"' && value[0] != '\'') {
+			if comment := strings.Index(value, " #"); comment >= 0 {
+				value = value[:comment]
+			}
+			if comment := strings.Index(value, "\t#"); comment >= 0 {
+				value = value[:comment]
+			}
+			source.add(key, strings.TrimSpace(value), pos)
+			continue
+		}
+
+		// quoted value, which may span multiple lines
+		quote := value[0]
+		raw := value[1:]
+
+		var result strings.Builder
+		for {
+			end, done := scanDotEnvQuoted(raw, quote, &result)
+			if done {
+				rest := strings.TrimSpace(raw[end+1:])
+				if rest != "" && rest[0] != '#' {
+					return SourceFile{}, ErrSyntax{Position: Position{File: name, Line: i + 1}, Message: "unexpected characters after closing quote"}
+				}
+				break
+			}
+
+			// continue with the next line
+			i++
+			if i >= len(lines) {
+				return SourceFile{}, ErrSyntax{Position: pos, Message: "unterminated quoted value"}
+			}
+			result.WriteByte('\n')
+			raw = lines[i]
+		}
+
+		source.add(key, result.String(), pos)
+	}
+
+	return source, nil
+}
+
+// scanDotEnvQuoted scans a quoted value from raw into result.
+// When the closing quote is found, returns its index and true.
+func scanDotEnvQuoted(raw string, quote byte, result *strings.Builder) (int, bool) {
+	for j := 0; j < len(raw); j++ {
+		c := raw[j]
+		switch {
+		case c == quote:
+			return j, true
+		case c == '\\' && quote == '"' && j+1 < len(raw):
+			j++
+			switch raw[j] {
+			case 'n':
+				result.WriteByte('\n')
+			case 'r':
+				result.WriteByte('\r')
+			case 't':
+				result.WriteByte('\t')
+			case '"', '\\', '$':
+				result.WriteByte(raw[j])
+			default:
+				result.WriteByte('\\')
+				result.WriteByte(raw[j])
+			}
+		default:
+			result.WriteByte(c)
+		}
+	}
+	return len(raw), false
+}
+
+// isDotEnvKey checks if key is a valid variable name
+func isDotEnvKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z'):
+		case i > 0 && (('0' <= c && c <= '9') || c == '.' || c == '-'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t'
+}
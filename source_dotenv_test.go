@@ -0,0 +1,130 @@
+package stringreader_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tkw1536/stringreader"
+)
+
+func TestParseDotEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name: "unquoted values",
+			content: `# a comment
+PLAIN=value
+  SPACED = spaced value
+export EXPORTED=exported
+COMMENT=value # comment
+HASH=value#hash
+EMPTY=
+`,
+			want: map[string]string{
+				"PLAIN":    "value",
+				"SPACED":   "spaced value",
+				"EXPORTED": "exported",
+				"COMMENT":  "value",
+				"HASH":     "value#hash",
+				"EMPTY":    "",
+			},
+		},
+		{
+			name: "quoted values",
+			content: `SINGLE='single \n # value'
+DOUBLE="double\n\t\"value\"\$ # not a comment" # comment
+MULTI="first
+second"
+`,
+			want: map[string]string{
+				"SINGLE": `single \n # value`,
+				"DOUBLE": "double\n\t\"value\"$ # not a comment",
+				"MULTI":  "first\nsecond",
+			},
+		},
+		{
+			name:    "missing equals",
+			content: "VALID=1\nINVALID\n",
+			wantErr: "test.env:2: expected '='",
+		},
+		{
+			name:    "invalid name",
+			content: "1NVALID=1\n",
+			wantErr: "test.env:1: invalid variable name \"1NVALID\"",
+		},
+		{
+			name:    "unterminated quote",
+			content: "\nUNTERMINATED=\"value\n\n",
+			wantErr: "test.env:2: unterminated quoted value",
+		},
+		{
+			name:    "trailing characters",
+			content: "TRAILING='value' trailing\n",
+			wantErr: "test.env:1: unexpected characters after closing quote",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, err := stringreader.ParseDotEnv("test.env", strings.NewReader(tt.content))
+			if tt.wantErr != "" {
+				var syntaxErr stringreader.ErrSyntax
+				if !errors.As(err, &syntaxErr) {
+					t.Fatalf("ParseDotEnv() err = %v, want ErrSyntax", err)
+				}
+				if syntaxErr.Error() != tt.wantErr {
+					t.Errorf("ParseDotEnv() err = %q, want = %q", syntaxErr.Error(), tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseDotEnv() err = %s, want = nil", err)
+			}
+
+			got := make(map[string]string)
+			for _, key := range source.Keys() {
+				got[key], _ = source.Lookup(key)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseDotEnv() = %q, want = %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func ExampleParseDotEnv() {
+	var marshal stringreader.Marshal
+	marshal.NameTag = "env"
+	marshal.RegisterStandardParsers()
+
+	source, err := stringreader.ParseDotEnv("example.env", strings.NewReader(`
+# the host to listen on
+export HOST=localhost
+PORT=eighty
+`))
+	if err != nil {
+		panic(err)
+	}
+
+	var config struct {
+		Host string `env:"HOST"`
+		Port uint16 `env:"PORT"`
+	}
+	err = marshal.Unmarshal(&config, source)
+
+	// the error contains the position of the invalid value
+	var parseErr stringreader.ErrFailedToParseField
+	if errors.As(err, &parseErr) {
+		pos, _ := parseErr.Position()
+		fmt.Printf("invalid value for %s at %s\n", parseErr.Source(), pos)
+	}
+
+	// Output:
+	// invalid value for PORT at example.env:4
+}
@@ -0,0 +1,59 @@
+package stringreader
+
+import (
+	"os"
+	"strings"
+)
+
+// SourceEnv implements Source by reading environment variables.
+//
+// Each key is prefixed with Prefix before it is looked up.
+// By default, the environment of the current process is used.
+// When Environ is non-nil, it is used instead; it should be of the form returned by os.Environ.
+//
+// LookupAll splits the value of the environment variable using Separator, like the PATH variable.
+// When Separator is empty, LookupAll returns a slice containing only the value.
+// Otherwise, a variable set to the empty string results in an empty slice.
+type SourceEnv struct {
+	Prefix    string
+	Separator string
+
+	Environ []string
+}
+
+func (s SourceEnv) Lookup(key string) (string, bool) {
+	key = s.Prefix + key
+	if s.Environ == nil {
+		return os.LookupEnv(key)
+	}
+
+	for _, kv := range s.Environ {
+		// names may start with an '=' on windows, so skip the first character.
+		if len(kv) == 0 {
+			continue
+		}
+		index := strings.IndexByte(kv[1:], '=') + 1
+		if index == 0 {
+			continue
+		}
+		if kv[:index] == key {
+			return kv[index+1:], true
+		}
+	}
+	return "", false
+}
+
+func (s SourceEnv) LookupAll(key string) ([]string, bool) {
+	value, ok := s.Lookup(key)
+	if !ok {
+		return nil, false
+	}
+	switch {
+	case s.Separator == "":
+		return []string{value}, true
+	case value == "":
+		return []string{}, true
+	default:
+		return strings.Split(value, s.Separator), true
+	}
+}
@@ -0,0 +1,59 @@
+package stringreader
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestSourceEnv(t *testing.T) {
+	const name = "STRINGREADER_TEST_SOURCE_ENV"
+	if err := os.Setenv(name, "from process"); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Unsetenv(name)
+
+	value, ok := SourceEnv{Prefix: "STRINGREADER_"}.Lookup("TEST_SOURCE_ENV")
+	if !ok || value != "from process" {
+		t.Errorf("SourceEnv.Lookup() = %q, %t, want = %q, true", value, ok, "from process")
+	}
+
+	_, ok = SourceEnv{Environ: []string{}}.Lookup(name)
+	if ok {
+		t.Error("SourceEnv.Lookup() with empty Environ found a variable from the process")
+	}
+}
+
+func ExampleSourceEnv() {
+	// Create a new SourceEnv with a prefix, and a custom environment.
+	var source Source = SourceEnv{
+		Prefix:    "APP_",
+		Separator: ":",
+
+		Environ: []string{
+			"APP_NAME=example",
+			"APP_PATH=/usr/bin:/bin",
+			"APP_EMPTY=",
+			"=C:=C:\\",
+			"NAME=not prefixed",
+		},
+	}
+
+	nameValue, nameOK := source.Lookup("NAME")
+	fmt.Printf("source.Lookup(%q) value=%q ok=%t\n", "NAME", nameValue, nameOK)
+
+	pathValue, pathOK := source.LookupAll("PATH")
+	fmt.Printf("source.LookupAll(%q) value=%q ok=%t\n", "PATH", pathValue, pathOK)
+
+	emptyValue, emptyOK := source.LookupAll("EMPTY")
+	fmt.Printf("source.LookupAll(%q) value=%q ok=%t\n", "EMPTY", emptyValue, emptyOK)
+
+	fakeValue, fakeOK := source.Lookup("FAKE")
+	fmt.Printf("source.Lookup(%q) value=%q ok=%t\n", "FAKE", fakeValue, fakeOK)
+
+	// Output:
+	// source.Lookup("NAME") value="example" ok=true
+	// source.LookupAll("PATH") value=["/usr/bin" "/bin"] ok=true
+	// source.LookupAll("EMPTY") value=[] ok=true
+	// source.Lookup("FAKE") value="" ok=false
+}
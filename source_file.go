@@ -0,0 +1,144 @@
+package stringreader
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// Position describes the location of a value within a file.
+type Position struct {
+	File string // name of the file, may be empty
+	Line int    // line number, starting at 1
+}
+
+func (p Position) String() string {
+	if p.File == "" {
+		return fmt.Sprintf("line %d", p.Line)
+	}
+	return fmt.Sprintf("%s:%d", p.File, p.Line)
+}
+
+// SourcePositioner is a Source that knows where each of its values was defined.
+//
+// When a parser fails to parse a value from a SourcePositioner, the returned ErrFailedToParseField contains the position of the value.
+type SourcePositioner interface {
+	Source
+
+	// Position returns the position of the value returned by Lookup.
+	// When the key does not exist, returns the zero Position and false.
+	Position(key string) (Position, bool)
+}
+
+// SourceFile implements SourcePositioner for values read from a file.
+// See ParseDotEnv on how to create a SourceFile.
+//
+// A key may be defined multiple times.
+// Lookup returns the last definition of a key, LookupAll returns all definitions in order.
+type SourceFile struct {
+	keys   []string // keys in the order of their first definition
+	values map[string][]fileValue
+}
+
+// fileValue is a single value within a SourceFile.
+type fileValue struct {
+	value string
+	pos   Position
+}
+
+// add adds a new definition of key to s
+func (s *SourceFile) add(key, value string, pos Position) {
+	if s.values == nil {
+		s.values = make(map[string][]fileValue)
+	}
+	if _, ok := s.values[key]; !ok {
+		s.keys = append(s.keys, key)
+	}
+	s.values[key] = append(s.values[key], fileValue{value: value, pos: pos})
+}
+
+// Keys returns all keys defined in s, in the order of their first definition.
+func (s SourceFile) Keys() []string {
+	return append([]string(nil), s.keys...)
+}
+
+func (s SourceFile) Lookup(key string) (string, bool) {
+	values := s.values[key]
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[len(values)-1].value, true
+}
+
+func (s SourceFile) LookupAll(key string) ([]string, bool) {
+	values, ok := s.values[key]
+	if !ok {
+		return nil, false
+	}
+	result := make([]string, len(values))
+	for i, v := range values {
+		result[i] = v.value
+	}
+	return result, true
+}
+
+func (s SourceFile) Position(key string) (Position, bool) {
+	values := s.values[key]
+	if len(values) == 0 {
+		return Position{}, false
+	}
+	return values[len(values)-1].pos, true
+}
+
+// Select returns a view of s containing the keys prefixed by name and a dot, with the prefix removed.
+// For files read by ParseINI, this corresponds to a section.
+// Selecting from the returned view selects a nested section, e.g. "outer.inner".
+//
+// Select always succeeds, even if there are no such keys.
+func (s SourceFile) Select(name string) (Source, bool) {
+	return fileSection{sourcePrefix{Source: s, prefix: name + "."}}, true
+}
+
+// fileSection is a section of a SourceFile, see SourceFile.Select.
+type fileSection struct {
+	sourcePrefix
+}
+
+func (s fileSection) Select(name string) (Source, bool) {
+	return fileSection{sourcePrefix{Source: s.Source, prefix: s.prefix + name + "."}}, true
+}
+
+// ErrSyntax indicates a syntax error when parsing a file into a SourceFile.
+type ErrSyntax struct {
+	Position
+	Message string
+}
+
+func (err ErrSyntax) Error() string {
+	return fmt.Sprintf("%s: %s", err.Position, err.Message)
+}
+
+// fileParser parses the content of a file into a SourceFile.
+type fileParser = func(name string, content string) (SourceFile, error)
+
+// parseFile reads all of r and then calls parser.
+func parseFile(parser fileParser, name string, r io.Reader) (SourceFile, error) {
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return SourceFile{}, err
+	}
+	return parser(name, strings.Replace(string(content), "\r\n", "\n", -1))
+}
+
+// readFile opens the file at path and parses it using parser.
+func readFile(parser fileParser, path string) (SourceFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return SourceFile{}, err
+	}
+	defer f.Close()
+
+	return parseFile(parser, path, f)
+}
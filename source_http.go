@@ -0,0 +1,105 @@
+package stringreader
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// SourceValues implements Source using url.Values, as used for query strings and form bodies.
+// Lookup returns the first value associated with a key.
+type SourceValues url.Values
+
+func (s SourceValues) Lookup(key string) (string, bool) {
+	values, ok := s[key]
+	if !ok || len(values) == 0 {
+		return "", false
+	}
+	return values[0], true
+}
+
+func (s SourceValues) LookupAll(key string) ([]string, bool) {
+	values, ok := s[key]
+	return values, ok
+}
+
+// SourceHeader implements Source using http.Header.
+// Keys are case-insensitive, they are canonicalized using http.CanonicalHeaderKey.
+// Lookup returns the first value associated with a key.
+type SourceHeader http.Header
+
+func (s SourceHeader) Lookup(key string) (string, bool) {
+	values, ok := s.LookupAll(key)
+	if !ok || len(values) == 0 {
+		return "", false
+	}
+	return values[0], true
+}
+
+func (s SourceHeader) LookupAll(key string) ([]string, bool) {
+	values, ok := s[http.CanonicalHeaderKey(key)]
+	return values, ok
+}
+
+// SourceCookies implements Source using a set of cookies, as returned by http.Request.Cookies.
+// Keys correspond to the name of cookies.
+// Lookup returns the value of the first cookie with the provided name.
+type SourceCookies []*http.Cookie
+
+func (s SourceCookies) Lookup(key string) (string, bool) {
+	for _, cookie := range s {
+		if cookie.Name == key {
+			return cookie.Value, true
+		}
+	}
+	return "", false
+}
+
+func (s SourceCookies) LookupAll(key string) ([]string, bool) {
+	var values []string
+	for _, cookie := range s {
+		if cookie.Name == key {
+			values = append(values, cookie.Value)
+		}
+	}
+	return values, values != nil
+}
+
+// maxRequestMemory is the maximum memory used to parse multipart forms.
+// This matches the default of the net/http package.
+const maxRequestMemory = 32 << 20
+
+// RequestSourceTag is the SourceTag used by UnmarshalRequest when m.SourceTag is empty.
+const RequestSourceTag = "in"
+
+// UnmarshalRequest unmarshals data from an http request into dest.
+// Any non-nil error returned implements UnmarshalError.
+//
+// The form of the request is parsed first; if this fails, an ErrInvalidRequest is returned.
+// Then UnmarshalContextual is called with the context of the request and a SourceSet holding the following sub-sources:
+//
+//   - "query": the url query parameters
+//   - "form": the parsed form body, see http.Request.PostForm
+//   - "header": the request headers
+//   - "cookie": the request cookies
+//
+// Fields that do not select a sub-source read from both the form body and query parameters, see http.Request.Form.
+// Sub-sources are selected using m.SourceTag; when it is empty, RequestSourceTag is used instead.
+func (m Marshal) UnmarshalRequest(dest interface{}, r *http.Request) error {
+	if err := r.ParseMultipartForm(maxRequestMemory); err != nil && err != http.ErrNotMultipart {
+		return ErrInvalidRequest{cause: err}
+	}
+
+	if m.SourceTag == "" {
+		m.SourceTag = RequestSourceTag
+	}
+
+	return m.UnmarshalContextual(r.Context(), dest, SourceSet{
+		Default: SourceValues(r.Form),
+		Sources: map[string]Source{
+			"query":  SourceValues(r.URL.Query()),
+			"form":   SourceValues(r.PostForm),
+			"header": SourceHeader(r.Header),
+			"cookie": SourceCookies(r.Cookies()),
+		},
+	}, ParsingData{})
+}
@@ -0,0 +1,82 @@
+package stringreader_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tkw1536/stringreader"
+)
+
+func ExampleSourceHeader() {
+	var source stringreader.Source = stringreader.SourceHeader(http.Header{
+		"Content-Type": {"text/plain"},
+		"Accept":       {"text/html", "text/plain"},
+	})
+
+	typeValue, typeOK := source.Lookup("content-type")
+	fmt.Printf("source.Lookup(%q) value=%q ok=%t\n", "content-type", typeValue, typeOK)
+
+	acceptValue, acceptOK := source.LookupAll("ACCEPT")
+	fmt.Printf("source.LookupAll(%q) value=%v ok=%t\n", "ACCEPT", acceptValue, acceptOK)
+
+	// Output:
+	// source.Lookup("content-type") value="text/plain" ok=true
+	// source.LookupAll("ACCEPT") value=[text/html text/plain] ok=true
+}
+
+func ExampleMarshal_UnmarshalRequest() {
+	var marshal stringreader.Marshal
+	marshal.NameTag = "name"
+	marshal.RegisterStandardParsers()
+
+	type Request struct {
+		Page    int    `name:"page" in:"query"`
+		Title   string `name:"title" in:"form"`
+		Agent   string `name:"user-agent" in:"header"`
+		Session string `name:"session" in:"cookie"`
+		Either  string `name:"either"`
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/?page=2&either=query", strings.NewReader("title=Hello&either=form"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("User-Agent", "example")
+	r.AddCookie(&http.Cookie{Name: "session", Value: "secret"})
+
+	var request Request
+	if err := marshal.UnmarshalRequest(&request, r); err != nil {
+		panic(err)
+	}
+	fmt.Printf("%+v\n", request)
+
+	// Output:
+	// {Page:2 Title:Hello Agent:example Session:secret Either:form}
+}
+
+func TestMarshal_UnmarshalRequest_errors(t *testing.T) {
+	var marshal stringreader.Marshal
+	marshal.RegisterStandardParsers()
+
+	r := httptest.NewRequest(http.MethodGet, "/?page=two", nil)
+
+	var unknown struct {
+		Page int `in:"body"`
+	}
+	var unknownErr stringreader.ErrUnknownSource
+	if err := marshal.UnmarshalRequest(&unknown, r); !errors.As(err, &unknownErr) || unknownErr.Name != "body" {
+		t.Errorf("Marshal.UnmarshalRequest() err = %v, want ErrUnknownSource", err)
+	}
+
+	var invalid struct {
+		Page int `name:"page" in:"query"`
+	}
+	marshal.NameTag = "name"
+
+	var parseErr stringreader.ErrFailedToParseField
+	if err := marshal.UnmarshalRequest(&invalid, r); !errors.As(err, &parseErr) || parseErr.Source() != "page" {
+		t.Errorf("Marshal.UnmarshalRequest() err = %v, want ErrFailedToParseField", err)
+	}
+}
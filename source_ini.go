@@ -0,0 +1,103 @@
+package stringreader
+
+import (
+	"io"
+	"strings"
+)
+
+// ParseINI parses an INI file from r into a SourceFile.
+// Name is the name of the file, and is used for positions and errors only.
+//
+// Each line of the file is either blank, a comment starting with ';' or '#', a section header of the form "[section]", or an assignment.
+// Assignments are of the form "key = value" or "key: value"; whitespace around keys and values is ignored.
+// A line following an assignment that is indented deeper than the assignment itself continues its value; the lines are joined with a newline.
+// Indentation is measured in spaces and tabs, each counting as one character.
+//
+// Keys within a section are prefixed with the name of the section and a dot, e.g. "section.key".
+// Keys that occur before the first section header are not prefixed.
+// Keys may be repeated, in which case LookupAll returns all values.
+//
+// To read a section into an inlined struct, select it as a sub-source using Marshal.SourceTag, see SourceFile.Select.
+//
+// When the file contains a syntax error, an ErrSyntax is returned.
+func ParseINI(name string, r io.Reader) (SourceFile, error) {
+	return parseFile(parseINI, name, r)
+}
+
+// ReadINI is like ParseINI, but reads the file at path.
+func ReadINI(path string) (SourceFile, error) {
+	return readFile(parseINI, path)
+}
+
+func parseINI(name string, content string) (source SourceFile, err error) {
+	var prefix string
+
+	// the assignment currently being read
+	var key, value string
+	var pos Position
+	var indent int // indentation of the assignment
+	var inAssignment bool
+
+	flush := func() {
+		if inAssignment {
+			source.add(key, value, pos)
+		}
+		inAssignment = false
+	}
+
+	for i, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		// continuation of the previous value
+		if inAssignment && trimmed != "" && indentation(line) > indent {
+			value += "\n" + trimmed
+			continue
+		}
+		flush()
+
+		if trimmed == "" || trimmed[0] == ';' || trimmed[0] == '#' {
+			continue
+		}
+
+		pos = Position{File: name, Line: i + 1}
+
+		// section header
+		if trimmed[0] == '[' {
+			if trimmed[len(trimmed)-1] != ']' {
+				return SourceFile{}, ErrSyntax{Position: pos, Message: "expected ']'"}
+			}
+			section := strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+			if section == "" {
+				return SourceFile{}, ErrSyntax{Position: pos, Message: "empty section name"}
+			}
+			prefix = section + "."
+			continue
+		}
+
+		// assignment
+		sep := strings.IndexAny(trimmed, "=:")
+		if sep < 0 {
+			return SourceFile{}, ErrSyntax{Position: pos, Message: "expected '=' or ':'"}
+		}
+		key = strings.TrimSpace(trimmed[:sep])
+		if key == "" {
+			return SourceFile{}, ErrSyntax{Position: pos, Message: "empty key"}
+		}
+		key = prefix + key
+		value = strings.TrimSpace(trimmed[sep+1:])
+		indent = indentation(line)
+		inAssignment = true
+	}
+	flush()
+
+	return source, nil
+}
+
+// indentation returns the number of spaces and tabs at the start of line.
+func indentation(line string) int {
+	i := 0
+	for i < len(line) && isSpace(line[i]) {
+		i++
+	}
+	return i
+}
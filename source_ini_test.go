@@ -0,0 +1,150 @@
+package stringreader_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tkw1536/stringreader"
+)
+
+func TestParseINI(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string][]string
+		wantErr string
+	}{
+		{
+			name: "sections and comments",
+			content: `; a comment
+# another comment
+global = value
+
+[section]
+key = value
+other: other value
+
+[nested.section]
+key=nested
+`,
+			want: map[string][]string{
+				"global":             {"value"},
+				"section.key":        {"value"},
+				"section.other":      {"other value"},
+				"nested.section.key": {"nested"},
+			},
+		},
+		{
+			name: "repeated keys and continuation lines",
+			content: `[list]
+item = first
+item = second
+text = first line
+  second line
+	third line
+after = value
+`,
+			want: map[string][]string{
+				"list.item":  {"first", "second"},
+				"list.text":  {"first line\nsecond line\nthird line"},
+				"list.after": {"value"},
+			},
+		},
+		{
+			name: "indented keys and continuation lines",
+			content: `[database]
+  host = x
+  port = 5
+  text = first line
+    second line
+  after = value
+	tabbed = value
+`,
+			want: map[string][]string{
+				"database.host":   {"x"},
+				"database.port":   {"5"},
+				"database.text":   {"first line\nsecond line"},
+				"database.after":  {"value"},
+				"database.tabbed": {"value"},
+			},
+		},
+		{
+			name:    "unterminated section",
+			content: "[section\n",
+			wantErr: "test.ini:1: expected ']'",
+		},
+		{
+			name:    "missing separator",
+			content: "[section]\nkey\n",
+			wantErr: "test.ini:2: expected '=' or ':'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, err := stringreader.ParseINI("test.ini", strings.NewReader(tt.content))
+			if tt.wantErr != "" {
+				var syntaxErr stringreader.ErrSyntax
+				if !errors.As(err, &syntaxErr) {
+					t.Fatalf("ParseINI() err = %v, want ErrSyntax", err)
+				}
+				if syntaxErr.Error() != tt.wantErr {
+					t.Errorf("ParseINI() err = %q, want = %q", syntaxErr.Error(), tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseINI() err = %s, want = nil", err)
+			}
+
+			got := make(map[string][]string)
+			for _, key := range source.Keys() {
+				got[key], _ = source.LookupAll(key)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseINI() = %q, want = %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func ExampleParseINI() {
+	var marshal stringreader.Marshal
+	marshal.NameTag = "ini"
+	marshal.ParserTag = "parser"
+	marshal.InlineParser = "inline"
+	marshal.SourceTag = "section"
+	marshal.RegisterStandardParsers()
+
+	type Database struct {
+		Host string `ini:"host"`
+		Port uint16 `ini:"port"`
+	}
+
+	type Config struct {
+		Name     string   `ini:"name"`
+		Database Database `parser:"inline" section:"database"`
+	}
+
+	source, err := stringreader.ParseINI("example.ini", strings.NewReader(`
+name = example
+
+[database]
+host = localhost
+port = 5432
+`))
+	if err != nil {
+		panic(err)
+	}
+
+	var config Config
+	if err := marshal.Unmarshal(&config, source); err != nil {
+		panic(err)
+	}
+	fmt.Printf("%+v\n", config)
+
+	// Output:
+	// {Name:example Database:{Host:localhost Port:5432}}
+}
@@ -0,0 +1,122 @@
+package stringreader
+
+// Layer is a named Source within SourceLayers.
+type Layer struct {
+	Name   string
+	Source Source
+}
+
+// SourceLayers implements Source by combining an ordered list of layers.
+// Layers that come first take precedence over later layers.
+//
+// Lookup returns the value from the first layer that contains the key.
+// By default, LookupAll behaves the same way.
+// When ConcatAll is true, LookupAll instead concatenates the values of all layers that contain the key, in order.
+//
+// Use LayerOf and LayersOfAll to find out which layers answered a key.
+type SourceLayers struct {
+	Layers    []Layer
+	ConcatAll bool
+}
+
+func (s SourceLayers) Lookup(key string) (string, bool) {
+	for _, layer := range s.Layers {
+		if value, ok := layer.Source.Lookup(key); ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+func (s SourceLayers) LookupAll(key string) ([]string, bool) {
+	var result []string
+	var found bool
+
+	for _, layer := range s.Layers {
+		values, ok := layer.Source.LookupAll(key)
+		if !ok {
+			continue
+		}
+		if !s.ConcatAll {
+			return values, true
+		}
+
+		result = append(result, values...)
+		found = true
+	}
+
+	return result, found
+}
+
+// LayerOf returns the name of the layer that answers Lookup for key.
+// When no layer contains the key, returns the empty string and false.
+func (s SourceLayers) LayerOf(key string) (string, bool) {
+	index := s.layerIndex(key)
+	if index < 0 {
+		return "", false
+	}
+	return s.Layers[index].Name, true
+}
+
+// LayersOfAll returns the names of the layers that answer LookupAll for key, in order.
+// When no layer contains the key, returns nil.
+func (s SourceLayers) LayersOfAll(key string) []string {
+	var names []string
+	for _, layer := range s.Layers {
+		if _, ok := layer.Source.LookupAll(key); !ok {
+			continue
+		}
+		names = append(names, layer.Name)
+		if !s.ConcatAll {
+			break
+		}
+	}
+	return names
+}
+
+// Position returns the position of the value returned by Lookup.
+// This is only available when the answering layer implements SourcePositioner.
+func (s SourceLayers) Position(key string) (Position, bool) {
+	index := s.layerIndex(key)
+	if index < 0 {
+		return Position{}, false
+	}
+	positioner, ok := s.Layers[index].Source.(SourcePositioner)
+	if !ok {
+		return Position{}, false
+	}
+	return positioner.Position(key)
+}
+
+// Select returns a new SourceLayers consisting of the named sub-source of each layer.
+// Layers that do not implement SourceSelector, or do not have such a sub-source, are omitted.
+// When no layer has such a sub-source, returns nil and false.
+func (s SourceLayers) Select(name string) (Source, bool) {
+	selected := SourceLayers{ConcatAll: s.ConcatAll}
+	for _, layer := range s.Layers {
+		selector, ok := layer.Source.(SourceSelector)
+		if !ok {
+			continue
+		}
+		source, ok := selector.Select(name)
+		if !ok {
+			continue
+		}
+		selected.Layers = append(selected.Layers, Layer{Name: layer.Name, Source: source})
+	}
+
+	if len(selected.Layers) == 0 {
+		return nil, false
+	}
+	return selected, true
+}
+
+// layerIndex returns the index of the layer that answers Lookup for key, or -1.
+func (s SourceLayers) layerIndex(key string) int {
+	for i, layer := range s.Layers {
+		if _, ok := layer.Source.Lookup(key); ok {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,66 @@
+package stringreader
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestSourceLayers_LookupAll(t *testing.T) {
+	layers := []Layer{
+		{Name: "flags", Source: SourceSmartSplit{SourceMulti: SourceMultiMap{"list": {"a"}}}},
+		{Name: "env", Source: SourceSmartSplit{SourceMulti: SourceMultiMap{"other": {"x"}}}},
+		{Name: "defaults", Source: SourceSmartSplit{SourceMulti: SourceMultiMap{"list": {"b", "c"}}}},
+	}
+
+	tests := []struct {
+		name       string
+		concatAll  bool
+		wantValues []string
+		wantLayers []string
+	}{
+		{"first layer", false, []string{"a"}, []string{"flags"}},
+		{"all layers", true, []string{"a", "b", "c"}, []string{"flags", "defaults"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := SourceLayers{Layers: layers, ConcatAll: tt.concatAll}
+
+			gotValues, gotOK := source.LookupAll("list")
+			if !gotOK || !reflect.DeepEqual(gotValues, tt.wantValues) {
+				t.Errorf("SourceLayers.LookupAll() = %v, %t, want = %v, true", gotValues, gotOK, tt.wantValues)
+			}
+
+			if gotLayers := source.LayersOfAll("list"); !reflect.DeepEqual(gotLayers, tt.wantLayers) {
+				t.Errorf("SourceLayers.LayersOfAll() = %v, want = %v", gotLayers, tt.wantLayers)
+			}
+
+			if gotValues, gotOK := source.LookupAll("missing"); gotOK || gotValues != nil {
+				t.Errorf("SourceLayers.LookupAll() = %v, %t, want = nil, false", gotValues, gotOK)
+			}
+		})
+	}
+}
+
+// Create a new SourceLayers where flags take precedence over the environment and defaults.
+func ExampleSourceLayers() {
+	var source = SourceLayers{
+		Layers: []Layer{
+			{Name: "flags", Source: SourceSmartSplit{SourceSingle: SourceSingleMap{"port": "8080"}}},
+			{Name: "env", Source: SourceEnv{Environ: []string{"host=example.com", "port=80"}}},
+			{Name: "defaults", Source: SourceSmartSplit{SourceSingle: SourceSingleMap{"host": "localhost", "user": "root"}}},
+		},
+	}
+
+	for _, key := range []string{"port", "host", "user", "fake"} {
+		value, ok := source.Lookup(key)
+		layer, _ := source.LayerOf(key)
+		fmt.Printf("source.Lookup(%q) value=%q ok=%t layer=%q\n", key, value, ok, layer)
+	}
+
+	// Output:
+	// source.Lookup("port") value="8080" ok=true layer="flags"
+	// source.Lookup("host") value="example.com" ok=true layer="env"
+	// source.Lookup("user") value="root" ok=true layer="defaults"
+	// source.Lookup("fake") value="" ok=false layer=""
+}
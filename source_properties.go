@@ -0,0 +1,157 @@
+package stringreader
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// ParseProperties parses a Java properties file from r into a SourceFile.
+// Name is the name of the file, and is used for positions and errors only.
+//
+// The format follows java.util.Properties, except that the file is read as UTF-8:
+//
+//   - lines starting with '#' or '!' are comments
+//   - keys are separated from values by '=', ':' or whitespace
+//   - a line ending with '\' is continued on the next line, ignoring leading whitespace
+//   - keys and values may contain the escape sequences \t, \n, \r, \f and \uXXXX; any other escaped character stands for itself
+//
+// Keys may be repeated, in which case LookupAll returns all values.
+//
+// When the file contains a syntax error, an ErrSyntax is returned.
+func ParseProperties(name string, r io.Reader) (SourceFile, error) {
+	return parseFile(parseProperties, name, r)
+}
+
+// ReadProperties is like ParseProperties, but reads the file at path.
+func ReadProperties(path string) (SourceFile, error) {
+	return readFile(parseProperties, path)
+}
+
+func parseProperties(name string, content string) (source SourceFile, err error) {
+	lines := strings.Split(content, "\n")
+	for i := 0; i < len(lines); i++ {
+		pos := Position{File: name, Line: i + 1}
+
+		line := strings.TrimLeft(lines[i], " \t\f")
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+
+		// join continued lines into a single logical line
+		for endsWithBackslash(line) && i+1 < len(lines) {
+			i++
+			line = line[:len(line)-1] + strings.TrimLeft(lines[i], " \t\f")
+		}
+		if endsWithBackslash(line) {
+			line = line[:len(line)-1]
+		}
+
+		// find the end of the key, skipping over escaped characters
+		end := 0
+		for end < len(line) {
+			c := line[end]
+			if c == '\\' {
+				end += 2
+				continue
+			}
+			if c == '=' || c == ':' || isPropertiesSpace(c) {
+				break
+			}
+			end++
+		}
+		if end > len(line) {
+			end = len(line)
+		}
+		rawKey, rawValue := line[:end], line[end:]
+
+		// skip whitespace, then an optional separator, then whitespace
+		rawValue = strings.TrimLeft(rawValue, " \t\f")
+		if rawValue != "" && (rawValue[0] == '=' || rawValue[0] == ':') {
+			rawValue = strings.TrimLeft(rawValue[1:], " \t\f")
+		}
+
+		key, err := unescapeProperties(rawKey)
+		if err != nil {
+			return SourceFile{}, ErrSyntax{Position: pos, Message: err.Error()}
+		}
+		value, err := unescapeProperties(rawValue)
+		if err != nil {
+			return SourceFile{}, ErrSyntax{Position: pos, Message: err.Error()}
+		}
+		source.add(key, value, pos)
+	}
+
+	return source, nil
+}
+
+// endsWithBackslash checks if line ends with an odd number of backslashes.
+func endsWithBackslash(line string) bool {
+	count := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		count++
+	}
+	return count%2 == 1
+}
+
+func isPropertiesSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\f'
+}
+
+// unescapeProperties replaces the escape sequences in a key or value of a properties file.
+func unescapeProperties(raw string) (string, error) {
+	if strings.IndexByte(raw, '\\') < 0 {
+		return raw, nil
+	}
+
+	var result strings.Builder
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		if c != '\\' || i+1 >= len(raw) {
+			result.WriteByte(c)
+			continue
+		}
+
+		i++
+		switch raw[i] {
+		case 't':
+			result.WriteByte('\t')
+		case 'n':
+			result.WriteByte('\n')
+		case 'r':
+			result.WriteByte('\r')
+		case 'f':
+			result.WriteByte('\f')
+		case 'u':
+			if i+5 > len(raw) {
+				return "", errMalformedUnicode
+			}
+			code, err := strconv.ParseUint(raw[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", errMalformedUnicode
+			}
+			i += 4
+
+			// combine surrogate pairs
+			r := rune(code)
+			if utf16.IsSurrogate(r) && i+7 <= len(raw) && raw[i+1:i+3] == "\\u" {
+				if low, err := strconv.ParseUint(raw[i+3:i+7], 16, 16); err == nil {
+					if pair := utf16.DecodeRune(r, rune(low)); pair != utf8.RuneError {
+						r = pair
+						i += 6
+					}
+				}
+			}
+
+			result.WriteRune(r)
+		default:
+			result.WriteByte(raw[i])
+		}
+	}
+	return result.String(), nil
+}
+
+var errMalformedUnicode = errors.New("malformed \\uXXXX escape sequence")
@@ -0,0 +1,127 @@
+package stringreader_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tkw1536/stringreader"
+)
+
+func TestParseProperties(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string][]string
+		wantErr string
+	}{
+		{
+			name: "separators and comments",
+			content: `# a comment
+! another comment
+equals=value
+colon:value
+space value
+  spaced   =   spaced value
+empty
+`,
+			want: map[string][]string{
+				"equals": {"value"},
+				"colon":  {"value"},
+				"space":  {"value"},
+				"spaced": {"spaced value"},
+				"empty":  {""},
+			},
+		},
+		{
+			name: "continuation lines",
+			content: `fruits = apple, banana, \
+         cherry
+backslash = value\\
+next = line
+`,
+			want: map[string][]string{
+				"fruits":    {"apple, banana, cherry"},
+				"backslash": {`value\`},
+				"next":      {"line"},
+			},
+		},
+		{
+			name: "escape sequences",
+			content: `key\ with\ spaces = value
+key\=with\:separators = value
+escapes = tab\tnewline\nother\q
+unicode = \u00e9\ud83d\ude00
+repeated = first
+repeated = second
+`,
+			want: map[string][]string{
+				"key with spaces":     {"value"},
+				"key=with:separators": {"value"},
+				"escapes":             {"tab\tnewline\notherq"},
+				"unicode":             {"é😀"},
+				"repeated":            {"first", "second"},
+			},
+		},
+		{
+			name:    "malformed unicode",
+			content: "\ninvalid = \\u12\n",
+			wantErr: "test.properties:2: malformed \\uXXXX escape sequence",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, err := stringreader.ParseProperties("test.properties", strings.NewReader(tt.content))
+			if tt.wantErr != "" {
+				var syntaxErr stringreader.ErrSyntax
+				if !errors.As(err, &syntaxErr) {
+					t.Fatalf("ParseProperties() err = %v, want ErrSyntax", err)
+				}
+				if syntaxErr.Error() != tt.wantErr {
+					t.Errorf("ParseProperties() err = %q, want = %q", syntaxErr.Error(), tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseProperties() err = %s, want = nil", err)
+			}
+
+			got := make(map[string][]string)
+			for _, key := range source.Keys() {
+				got[key], _ = source.LookupAll(key)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseProperties() = %q, want = %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func ExampleParseProperties() {
+	var marshal stringreader.Marshal
+	marshal.NameTag = "property"
+	marshal.RegisterStandardParsers()
+
+	source, err := stringreader.ParseProperties("example.properties", strings.NewReader(`
+# database configuration
+database.url = jdbc:postgresql://localhost/example
+database.pool.size : 10
+`))
+	if err != nil {
+		panic(err)
+	}
+
+	var config struct {
+		URL      string `property:"database.url"`
+		PoolSize int    `property:"database.pool.size"`
+	}
+	if err := marshal.Unmarshal(&config, source); err != nil {
+		panic(err)
+	}
+	fmt.Printf("%+v\n", config)
+
+	// Output:
+	// {URL:jdbc:postgresql://localhost/example PoolSize:10}
+}
@@ -180,3 +180,27 @@ func ExampleSourceSmartSplit_empty() {
 	// source.LookupAll("key") value=[] ok=false
 	// source.LookupAll("fake") value=[] ok=false
 }
+
+// Create a new SourcePrefix that prefixes every key.
+func ExampleSourcePrefix() {
+	var source = SourcePrefix(SourceSmartSplit{
+		SourceSingle: SourceSingleMap(map[string]string{
+			"app.key": "value",
+			"key":     "unprefixed",
+		}),
+	}, "app.")
+
+	keyValue, keyOK := source.Lookup("key")
+	fmt.Printf("source.Lookup(%q) value=%q ok=%t\n", "key", keyValue, keyOK)
+
+	mKeyValue, mKeyOK := source.LookupAll("key")
+	fmt.Printf("source.LookupAll(%q) value=%v ok=%t\n", "key", mKeyValue, mKeyOK)
+
+	fakeValue, fakeOK := source.Lookup("app.key")
+	fmt.Printf("source.Lookup(%q) value=%q ok=%t\n", "app.key", fakeValue, fakeOK)
+
+	// Output:
+	// source.Lookup("key") value="value" ok=true
+	// source.LookupAll("key") value=[value] ok=true
+	// source.Lookup("app.key") value="" ok=false
+}
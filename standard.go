@@ -0,0 +1,474 @@
+package stringreader
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StandardDefaultParser is the name of the standard parser that automatically parses a value based on the type of the destination field.
+// It supports strings, booleans, all integer, float and complex types, as well as []byte (using base64).
+//
+// RegisterStandardParsers uses it as DefaultParser, if no other DefaultParser is set.
+const StandardDefaultParser = "auto"
+
+// StandardParsers returns a new map containing the standard SingleParsers.
+//
+// The parsers are named after the type they produce:
+// "string", "bool", "int", "int8", "int16", "int32", "int64",
+// "uint", "uint8", "uint16", "uint32", "uint64",
+// "float32", "float64", "complex64" and "complex128".
+// Furthermore, "base64" and "hex" produce a []byte, and StandardDefaultParser picks one of the above based on the destination type.
+// Finally, "json" decodes a JSON value into a new value of the destination type,
+// and "split" splits a value into a []string using the separator given by the "sep" argument, or a comma by default.
+//
+// The parsers "trim", "lower" and "upper" transform a string, and are intended to be used as stages of a parser pipeline, e.g. "trim|lower|oneof".
+// The parser "oneof" accepts only values that are one of the space-separated words in the "values" argument, e.g. "oneof(values=debug info)".
+//
+// The integer parsers, as well as StandardDefaultParser for integer types, accept the arguments "base" and "bits", e.g. "int(base=16,bits=8)".
+// Base is the base of the value, as accepted by strconv.ParseInt, and defaults to 10.
+// Bits limits the size of the value, and defaults to the size of the destination type.
+//
+// When a value does not exist, each parser returns the zero value of its type.
+// When a value can not be parsed, the underlying error of the strconv or encoding package is returned.
+func StandardParsers() map[string]SingleParser {
+	return map[string]SingleParser{
+		StandardDefaultParser: parseAuto,
+
+		"string": func(value string, ok bool, ctx UnmarshalContext) (interface{}, error) {
+			return value, nil
+		},
+		"bool": func(value string, ok bool, ctx UnmarshalContext) (interface{}, error) {
+			if !ok {
+				return false, nil
+			}
+			return strconv.ParseBool(value)
+		},
+
+		"int":   parseInt(strconv.IntSize, func(i int64) interface{} { return int(i) }),
+		"int8":  parseInt(8, func(i int64) interface{} { return int8(i) }),
+		"int16": parseInt(16, func(i int64) interface{} { return int16(i) }),
+		"int32": parseInt(32, func(i int64) interface{} { return int32(i) }),
+		"int64": parseInt(64, func(i int64) interface{} { return i }),
+
+		"uint":   parseUint(strconv.IntSize, func(u uint64) interface{} { return uint(u) }),
+		"uint8":  parseUint(8, func(u uint64) interface{} { return uint8(u) }),
+		"uint16": parseUint(16, func(u uint64) interface{} { return uint16(u) }),
+		"uint32": parseUint(32, func(u uint64) interface{} { return uint32(u) }),
+		"uint64": parseUint(64, func(u uint64) interface{} { return u }),
+
+		"float32": parseFloat(32, func(f float64) interface{} { return float32(f) }),
+		"float64": parseFloat(64, func(f float64) interface{} { return f }),
+
+		"complex64":  parseComplex(64, func(c complex128) interface{} { return complex64(c) }),
+		"complex128": parseComplex(128, func(c complex128) interface{} { return c }),
+
+		"base64": func(value string, ok bool, ctx UnmarshalContext) (interface{}, error) {
+			if !ok {
+				return []byte(nil), nil
+			}
+			return base64.StdEncoding.DecodeString(value)
+		},
+		"hex": func(value string, ok bool, ctx UnmarshalContext) (interface{}, error) {
+			if !ok {
+				return []byte(nil), nil
+			}
+			return hex.DecodeString(value)
+		},
+		"json": parseJSON,
+
+		"trim": func(value string, ok bool, ctx UnmarshalContext) (interface{}, error) {
+			return strings.TrimSpace(value), nil
+		},
+		"lower": func(value string, ok bool, ctx UnmarshalContext) (interface{}, error) {
+			return strings.ToLower(value), nil
+		},
+		"upper": func(value string, ok bool, ctx UnmarshalContext) (interface{}, error) {
+			return strings.ToUpper(value), nil
+		},
+		"oneof": func(value string, ok bool, ctx UnmarshalContext) (interface{}, error) {
+			if !ok {
+				return "", nil
+			}
+			for _, option := range strings.Fields(ctx.Args()["values"]) {
+				if value == option {
+					return value, nil
+				}
+			}
+			return nil, fmt.Errorf("value %q is not one of %q", value, ctx.Args()["values"])
+		},
+
+		"split": func(value string, ok bool, ctx UnmarshalContext) (interface{}, error) {
+			if !ok || value == "" {
+				return []string(nil), nil
+			}
+			return strings.Split(value, splitSep(ctx)), nil
+		},
+	}
+}
+
+// StandardFormatters returns a new map containing the standard SingleFormatters.
+// They use the same names as StandardParsers, and produce values that can be read by the parser of the same name.
+func StandardFormatters() map[string]SingleFormatter {
+	formatters := map[string]SingleFormatter{
+		StandardDefaultParser: formatAuto,
+
+		"base64": func(value interface{}, ctx UnmarshalContext) (string, bool, error) {
+			bytes, err := toBytes(value)
+			if err != nil {
+				return "", false, err
+			}
+			return base64.StdEncoding.EncodeToString(bytes), true, nil
+		},
+		"hex": func(value interface{}, ctx UnmarshalContext) (string, bool, error) {
+			bytes, err := toBytes(value)
+			if err != nil {
+				return "", false, err
+			}
+			return hex.EncodeToString(bytes), true, nil
+		},
+		"split": func(value interface{}, ctx UnmarshalContext) (string, bool, error) {
+			strs, err := toStrings(value)
+			if err != nil {
+				return "", false, err
+			}
+			return strings.Join(strs, splitSep(ctx)), true, nil
+		},
+		"json": func(value interface{}, ctx UnmarshalContext) (string, bool, error) {
+			bytes, err := json.Marshal(value)
+			if err != nil {
+				return "", false, err
+			}
+			return string(bytes), true, nil
+		},
+	}
+
+	// all the other types can be formatted based on their kind
+	for _, name := range []string{
+		"string", "bool", "trim", "lower", "upper", "oneof",
+		"int", "int8", "int16", "int32", "int64",
+		"uint", "uint8", "uint16", "uint32", "uint64",
+		"float32", "float64", "complex64", "complex128",
+	} {
+		formatters[name] = formatAuto
+	}
+
+	return formatters
+}
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	urlPtrType   = reflect.TypeOf((*url.URL)(nil))
+)
+
+// StandardTypeParsers returns a new map containing the standard type parsers.
+//
+// It contains parsers for time.Duration (using time.ParseDuration) and *url.URL (using url.Parse).
+// When a value does not exist, each parser returns the zero value of its type.
+func StandardTypeParsers() map[reflect.Type]SingleParser {
+	return map[reflect.Type]SingleParser{
+		durationType: func(value string, ok bool, ctx UnmarshalContext) (interface{}, error) {
+			if !ok {
+				return time.Duration(0), nil
+			}
+			return time.ParseDuration(value)
+		},
+		urlPtrType: func(value string, ok bool, ctx UnmarshalContext) (interface{}, error) {
+			if !ok {
+				return (*url.URL)(nil), nil
+			}
+			return url.Parse(value)
+		},
+	}
+}
+
+// StandardTypeFormatters returns a new map containing the standard type formatters.
+// They use the same types as StandardTypeParsers, and produce values that can be read by the parser of the same type.
+func StandardTypeFormatters() map[reflect.Type]SingleFormatter {
+	return map[reflect.Type]SingleFormatter{
+		durationType: func(value interface{}, ctx UnmarshalContext) (string, bool, error) {
+			return value.(time.Duration).String(), true, nil
+		},
+		urlPtrType: func(value interface{}, ctx UnmarshalContext) (string, bool, error) {
+			u := value.(*url.URL)
+			if u == nil {
+				return "", false, nil
+			}
+			return u.String(), true, nil
+		},
+	}
+}
+
+// RegisterStandardParsers registers StandardParsers, StandardFormatters, StandardTypeParsers and StandardTypeFormatters with m.
+// Existing parsers and formatters of the same name or type are overwritten.
+//
+// When m.DefaultParser is empty, it is set to StandardDefaultParser.
+func (m *Marshal) RegisterStandardParsers() {
+	for name, parser := range StandardParsers() {
+		m.RegisterSingleParser(name, parser)
+	}
+	for name, formatter := range StandardFormatters() {
+		m.RegisterSingleFormatter(name, formatter)
+	}
+	for typ, parser := range StandardTypeParsers() {
+		m.RegisterTypeParser(typ, parser)
+	}
+	for typ, formatter := range StandardTypeFormatters() {
+		m.RegisterTypeFormatter(typ, formatter)
+	}
+
+	if m.DefaultParser == "" {
+		m.DefaultParser = StandardDefaultParser
+	}
+}
+
+// intArgs reads the "base" and "bits" arguments of an integer parser from ctx.
+// Base defaults to 10, and bits to the size of the destination type, which it may not exceed.
+func intArgs(ctx UnmarshalContext, size int) (base, bits int, err error) {
+	base, bits = 10, size
+
+	args := ctx.Args()
+	if value, ok := args["base"]; ok {
+		base, err = strconv.Atoi(value)
+		if err != nil || base == 1 || base < 0 || base > 36 {
+			return 0, 0, fmt.Errorf("invalid argument base=%q: must be 0 or between 2 and 36", value)
+		}
+	}
+	if value, ok := args["bits"]; ok {
+		bits, err = strconv.Atoi(value)
+		if err != nil || bits < 1 || bits > size {
+			return 0, 0, fmt.Errorf("invalid argument bits=%q: must be between 1 and %d", value, size)
+		}
+	}
+	return base, bits, nil
+}
+
+func parseInt(bits int, convert func(int64) interface{}) SingleParser {
+	return func(value string, ok bool, ctx UnmarshalContext) (interface{}, error) {
+		if !ok {
+			return convert(0), nil
+		}
+		base, bits, err := intArgs(ctx, bits)
+		if err != nil {
+			return nil, err
+		}
+		i, err := strconv.ParseInt(value, base, bits)
+		if err != nil {
+			return nil, err
+		}
+		return convert(i), nil
+	}
+}
+
+func parseUint(bits int, convert func(uint64) interface{}) SingleParser {
+	return func(value string, ok bool, ctx UnmarshalContext) (interface{}, error) {
+		if !ok {
+			return convert(0), nil
+		}
+		base, bits, err := intArgs(ctx, bits)
+		if err != nil {
+			return nil, err
+		}
+		u, err := strconv.ParseUint(value, base, bits)
+		if err != nil {
+			return nil, err
+		}
+		return convert(u), nil
+	}
+}
+
+func parseFloat(bits int, convert func(float64) interface{}) SingleParser {
+	return func(value string, ok bool, ctx UnmarshalContext) (interface{}, error) {
+		if !ok {
+			return convert(0), nil
+		}
+		f, err := strconv.ParseFloat(value, bits)
+		if err != nil {
+			return nil, err
+		}
+		return convert(f), nil
+	}
+}
+
+func parseComplex(bits int, convert func(complex128) interface{}) SingleParser {
+	return func(value string, ok bool, ctx UnmarshalContext) (interface{}, error) {
+		if !ok {
+			return convert(0), nil
+		}
+		c, err := strconv.ParseComplex(value, bits)
+		if err != nil {
+			return nil, err
+		}
+		return convert(c), nil
+	}
+}
+
+var (
+	bytesType   = reflect.TypeOf([]byte(nil))
+	stringsType = reflect.TypeOf([]string(nil))
+)
+
+// toBytes converts value, a string or a byte slice of any named type, into a []byte.
+func toBytes(value interface{}) ([]byte, error) {
+	rValue := reflect.ValueOf(value)
+	switch {
+	case rValue.Kind() == reflect.String:
+	case rValue.Kind() == reflect.Slice && rValue.Type().ConvertibleTo(bytesType):
+	default:
+		return nil, fmt.Errorf("type %T is neither a string nor a byte slice", value)
+	}
+	return rValue.Convert(bytesType).Interface().([]byte), nil
+}
+
+// toStrings converts value, a slice of strings of any named type, into a []string.
+func toStrings(value interface{}) ([]string, error) {
+	rValue := reflect.ValueOf(value)
+	if rValue.Kind() != reflect.Slice || rValue.Type().Elem().Kind() != reflect.String {
+		return nil, fmt.Errorf("type %T is not a slice of strings", value)
+	}
+	if rValue.Type().ConvertibleTo(stringsType) {
+		return rValue.Convert(stringsType).Interface().([]string), nil
+	}
+
+	// the element type is a named string type
+	strs := make([]string, rValue.Len())
+	for i := range strs {
+		strs[i] = rValue.Index(i).String()
+	}
+	return strs, nil
+}
+
+// splitSep returns the separator of the "split" parser, given by the "sep" argument.
+func splitSep(ctx UnmarshalContext) string {
+	if sep, ok := ctx.Args()["sep"]; ok {
+		return sep
+	}
+	return ","
+}
+
+// parseJSON decodes value into a new value of the type of the destination field.
+func parseJSON(value string, ok bool, ctx UnmarshalContext) (interface{}, error) {
+	typ := ctx.Type()
+	if typ == nil {
+		return nil, errNoType
+	}
+
+	result := reflect.New(typ)
+	if ok {
+		if err := json.Unmarshal([]byte(value), result.Interface()); err != nil {
+			return nil, err
+		}
+	}
+	return result.Elem().Interface(), nil
+}
+
+// parseAuto parses value based on the type of the destination field.
+func parseAuto(value string, ok bool, ctx UnmarshalContext) (interface{}, error) {
+	typ := ctx.Type()
+	if typ == nil {
+		return nil, errNoType
+	}
+
+	result := reflect.New(typ).Elem()
+	if !ok {
+		return result.Interface(), nil
+	}
+
+	var err error
+	switch typ.Kind() {
+	case reflect.String:
+		result.SetString(value)
+	case reflect.Bool:
+		var b bool
+		b, err = strconv.ParseBool(value)
+		result.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		base, bits, aErr := intArgs(ctx, typ.Bits())
+		if aErr != nil {
+			return nil, aErr
+		}
+		var i int64
+		i, err = strconv.ParseInt(value, base, bits)
+		result.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		base, bits, aErr := intArgs(ctx, typ.Bits())
+		if aErr != nil {
+			return nil, aErr
+		}
+		var u uint64
+		u, err = strconv.ParseUint(value, base, bits)
+		result.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		f, err = strconv.ParseFloat(value, typ.Bits())
+		result.SetFloat(f)
+	case reflect.Complex64, reflect.Complex128:
+		var c complex128
+		c, err = strconv.ParseComplex(value, typ.Bits())
+		result.SetComplex(c)
+	case reflect.Slice:
+		if typ.Elem().Kind() != reflect.Uint8 {
+			return nil, fmt.Errorf("no standard parser for type %s", typ)
+		}
+		var b []byte
+		b, err = base64.StdEncoding.DecodeString(value)
+		result.Set(reflect.ValueOf(b).Convert(typ))
+	default:
+		return nil, fmt.Errorf("no standard parser for type %s", typ)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return result.Interface(), nil
+}
+
+// formatAuto formats value based on its type.
+func formatAuto(value interface{}, ctx UnmarshalContext) (string, bool, error) {
+	rValue := reflect.ValueOf(value)
+	switch rValue.Kind() {
+	case reflect.String:
+		return rValue.String(), true, nil
+	case reflect.Bool:
+		return strconv.FormatBool(rValue.Bool()), true, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		base, _, err := intArgs(ctx, rValue.Type().Bits())
+		if err != nil {
+			return "", false, err
+		}
+		return strconv.FormatInt(rValue.Int(), formatBase(base)), true, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		base, _, err := intArgs(ctx, rValue.Type().Bits())
+		if err != nil {
+			return "", false, err
+		}
+		return strconv.FormatUint(rValue.Uint(), formatBase(base)), true, nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(rValue.Float(), 'g', -1, rValue.Type().Bits()), true, nil
+	case reflect.Complex64, reflect.Complex128:
+		return strconv.FormatComplex(rValue.Complex(), 'g', -1, rValue.Type().Bits()), true, nil
+	case reflect.Slice:
+		if rValue.Type().ConvertibleTo(bytesType) {
+			return base64.StdEncoding.EncodeToString(rValue.Convert(bytesType).Interface().([]byte)), true, nil
+		}
+	}
+	return "", false, fmt.Errorf("no standard formatter for type %T", value)
+}
+
+// formatBase returns the base to format an integer in.
+// A base of 0 lets the parser detect the base from a prefix, so the value is formatted in base 10.
+func formatBase(base int) int {
+	if base == 0 {
+		return 10
+	}
+	return base
+}
+
+var errNoType = errors.New("standard parser requires the type of the destination field")
@@ -0,0 +1,287 @@
+package stringreader_test
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/tkw1536/stringreader"
+)
+
+func TestStandardParsers(t *testing.T) {
+	type Everything struct {
+		String     string
+		Bool       bool
+		Int        int
+		Int8       int8
+		Uint16     uint16
+		Float32    float32
+		Complex128 complex128
+		Bytes      []byte
+		Hex        []byte `parser:"hex"`
+		Missing    int64
+	}
+
+	var m stringreader.Marshal
+	m.ParserTag = "parser"
+	m.RegisterStandardParsers()
+
+	var got Everything
+	err := m.UnmarshalSingle(&got, stringreader.SourceSingleMap{
+		"String":     "hello",
+		"Bool":       "true",
+		"Int":        "-42",
+		"Int8":       "127",
+		"Uint16":     "65535",
+		"Float32":    "1.5",
+		"Complex128": "(1+2i)",
+		"Bytes":      "aGVsbG8=",
+		"Hex":        "776f726c64",
+	})
+	if err != nil {
+		t.Fatalf("Marshal.UnmarshalSingle() err = %s, want = nil", err)
+	}
+
+	want := Everything{
+		String:     "hello",
+		Bool:       true,
+		Int:        -42,
+		Int8:       127,
+		Uint16:     65535,
+		Float32:    1.5,
+		Complex128: 1 + 2i,
+		Bytes:      []byte("hello"),
+		Hex:        []byte("world"),
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Marshal.UnmarshalSingle() dest = %v, want = %v", got, want)
+	}
+
+	// round-trip using the standard formatters
+	single, _, err := m.Marshal(got)
+	if err != nil {
+		t.Fatalf("Marshal.Marshal() err = %s, want = nil", err)
+	}
+
+	var again Everything
+	if err := m.UnmarshalSingle(&again, single); err != nil {
+		t.Fatalf("Marshal.UnmarshalSingle() err = %s, want = nil", err)
+	}
+	if fmt.Sprint(again) != fmt.Sprint(want) {
+		t.Errorf("Marshal.UnmarshalSingle() round-trip dest = %v, want = %v", again, want)
+	}
+}
+
+// Key is a named byte slice type.
+type Key []byte
+
+func TestStandardFormatters_bytes(t *testing.T) {
+	type Bytes struct {
+		Key    Key    `parser:"base64"`
+		HexKey Key    `parser:"hex"`
+		Text   string `parser:"base64"`
+	}
+
+	var m stringreader.Marshal
+	m.ParserTag = "parser"
+	m.RegisterStandardParsers()
+
+	want := Bytes{Key: Key("hello"), HexKey: Key("world"), Text: "text"}
+	single, _, err := m.Marshal(want)
+	if err != nil {
+		t.Fatalf("Marshal.Marshal() err = %s, want = nil", err)
+	}
+
+	var got Bytes
+	if err := m.UnmarshalSingle(&got, single); err != nil {
+		t.Fatalf("Marshal.UnmarshalSingle() err = %s, want = nil", err)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Marshal.UnmarshalSingle() round-trip dest = %v, want = %v", got, want)
+	}
+
+	// values that are neither strings nor byte slices are rejected
+	_, _, err = m.Marshal(struct {
+		Number int `parser:"hex"`
+	}{})
+	var fErr stringreader.ErrFailedToFormatField
+	if !errors.As(err, &fErr) {
+		t.Errorf("Marshal.Marshal() err = %v, want ErrFailedToFormatField", err)
+	}
+}
+
+func TestStandardParsers_error(t *testing.T) {
+	var m stringreader.Marshal
+	m.RegisterStandardParsers()
+
+	var dest struct {
+		Small int8
+	}
+	err := m.UnmarshalSingle(&dest, stringreader.SourceSingleMap{
+		"Small": "1000",
+	})
+
+	var parseErr stringreader.ErrFailedToParseField
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("Marshal.UnmarshalSingle() err = %v, want ErrFailedToParseField", err)
+	}
+	if parseErr.Dest() != "Small" {
+		t.Errorf("ErrFailedToParseField.Dest() = %q, want = %q", parseErr.Dest(), "Small")
+	}
+	if !errors.Is(err, strconv.ErrRange) {
+		t.Errorf("Marshal.UnmarshalSingle() err = %v, want wrapping strconv.ErrRange", err)
+	}
+}
+
+func ExampleMarshal_RegisterStandardParsers() {
+	// create a new marshal with the standard parsers.
+	// this sets a default parser that can decode most basic types.
+	var marshal stringreader.Marshal
+	marshal.RegisterStandardParsers()
+
+	type Config struct {
+		Host    string
+		Port    uint16
+		Verbose bool
+	}
+
+	var config Config
+	err := marshal.UnmarshalSingle(&config, stringreader.SourceSingleMap{
+		"Host":    "localhost",
+		"Port":    "8080",
+		"Verbose": "true",
+	})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("%v\n", config)
+
+	// Output:
+	// {localhost 8080 true}
+}
+
+func ExampleStandardTypeParsers() {
+	var m stringreader.Marshal
+	m.RegisterStandardParsers()
+
+	// no tags are needed for types with a type parser
+	var config struct {
+		Timeout  time.Duration
+		Endpoint *url.URL
+	}
+
+	err := m.UnmarshalSingle(&config, stringreader.SourceSingleMap{
+		"Timeout":  "1m30s",
+		"Endpoint": "https://example.com/api",
+	})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(config.Timeout.Seconds(), config.Endpoint.Host)
+
+	single, _, err := m.Marshal(config)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(single["Timeout"], single["Endpoint"])
+
+	// Output:
+	// 90 example.com
+	// 1m30s https://example.com/api
+}
+
+func ExampleStandardParsers_json() {
+	var m stringreader.Marshal
+	m.ParserTag = "type"
+	m.RegisterStandardParsers()
+
+	var dest struct {
+		Weights map[string]float64 `type:"json"`
+		Tags    []string           `type:"json"`
+	}
+
+	err := m.UnmarshalSingle(&dest, stringreader.SourceSingleMap{
+		"Weights": `{"a": 0.25, "b": 0.75}`,
+		"Tags":    `["x", "y"]`,
+	})
+	fmt.Println(err, dest.Weights["b"], dest.Tags)
+
+	// Output:
+	// <nil> 0.75 [x y]
+}
+
+func ExampleStandardParsers_args() {
+	var m stringreader.Marshal
+	m.ParserTag = "type"
+	m.RegisterStandardParsers()
+
+	var dest struct {
+		Mask  int      `type:"int(base=16,bits=8)"`
+		Mode  uint32   `type:"auto(base=8)"`
+		Paths []string `type:"split(sep=;)"`
+	}
+
+	err := m.UnmarshalSingle(&dest, stringreader.SourceSingleMap{
+		"Mask":  "7f",
+		"Mode":  "755",
+		"Paths": "/bin;/usr/bin",
+	})
+	fmt.Println(err, dest)
+
+	// values that do not fit into the given bits are rejected
+	err = m.UnmarshalSingle(&dest, stringreader.SourceSingleMap{"Mask": "ff"})
+	fmt.Println(err)
+
+	single, _, _ := m.Marshal(dest)
+	fmt.Println(single["Mask"], single["Mode"], single["Paths"])
+
+	// Output:
+	// <nil> {127 493 [/bin /usr/bin]}
+	// Marshal.Unmarshal: Failed to parse field "Mask": strconv.ParseInt: parsing "ff": value out of range
+	// 7f 755 /bin;/usr/bin
+}
+
+// Names is a named slice of strings.
+type Names []string
+
+func TestStandardFormatters_split(t *testing.T) {
+	type Tag string
+	type Split struct {
+		Names Names `parser:"split(sep=;)"`
+		Tags  []Tag `parser:"split"`
+	}
+
+	var m stringreader.Marshal
+	m.ParserTag = "parser"
+	m.RegisterStandardParsers()
+
+	single, _, err := m.Marshal(Split{Names: Names{"a", "b"}, Tags: []Tag{"x", "y"}})
+	if err != nil {
+		t.Fatalf("Marshal.Marshal() err = %s, want = nil", err)
+	}
+	if single["Names"] != "a;b" || single["Tags"] != "x,y" {
+		t.Errorf("Marshal.Marshal() = %v", single)
+	}
+
+	var got struct {
+		Names Names `parser:"split(sep=;)"`
+	}
+	if err := m.UnmarshalSingle(&got, single); err != nil {
+		t.Fatalf("Marshal.UnmarshalSingle() err = %s, want = nil", err)
+	}
+	if fmt.Sprint(got.Names) != "[a b]" {
+		t.Errorf("Marshal.UnmarshalSingle() Names = %v, want = [a b]", got.Names)
+	}
+
+	// values that are not slices of strings are rejected
+	_, _, err = m.Marshal(struct {
+		Numbers []int `parser:"split"`
+	}{})
+	var fErr stringreader.ErrFailedToFormatField
+	if !errors.As(err, &fErr) {
+		t.Errorf("Marshal.Marshal() err = %v, want ErrFailedToFormatField", err)
+	}
+}
@@ -2,27 +2,82 @@
 package stringreader
 
 import (
+	"context"
 	"fmt"
 	"reflect"
+	"runtime/debug"
+	"strings"
 	"sync"
 )
 
 // Marshal can marshal and unmarshal data from a string-to-string hashmap
-// See the UnmarshalState function for details.
+// See the UnmarshalState and MarshalState functions for details.
 type Marshal struct {
 	NameTag       string // Optional, tag to read name from
 	StrictNameTag bool   // When false, allow fallback to field name
 
+	// NameMapper maps the field name to a key, when falling back to the field name (optional).
+	// See SnakeCase, ScreamingSnakeCase, KebabCase, CamelCase and JoinPath.
+	NameMapper NameMapper
+
 	ParserTag     string // tag to read parser from
 	DefaultParser string // default parser to fall back to (optional)
 	InlineParser  string // parser name to use for recursive struct parsing (optional)
 
-	// Known set of parsers
+	// Known set of parsers.
+	// Once the Marshal has been used, parsers should only be added using the Register methods, see UnmarshalState.
 	SingleParsers map[string]SingleParser
 	MultiParsers  map[string]MultiParser
 
+	// Known set of parsers, keyed by the type of field they parse.
+	// They are used for fields without a parser tag, see GetFieldParser.
+	// Like above, they should only be added using the Register methods once the Marshal has been used.
+	TypeParsers      map[reflect.Type]SingleParser
+	TypeMultiParsers map[reflect.Type]MultiParser
+
+	// Known set of formatters, keyed by the same names as the parsers.
+	// Only used by Marshal.Marshal.
+	SingleFormatters map[string]SingleFormatter
+	MultiFormatters  map[string]MultiFormatter
+
+	// Known set of formatters, keyed by the same types as the type parsers.
+	// Only used by Marshal.Marshal.
+	TypeFormatters      map[reflect.Type]SingleFormatter
+	TypeMultiFormatters map[reflect.Type]MultiFormatter
+
+	// SourceTag is the tag to select a sub-source from a SourceSelector (optional).
+	// Inlined structs read all their fields from the selected sub-source.
+	SourceTag string
+
+	// DefaultTag is the tag to read default values from (optional).
+	// Default values are used when a key does not exist in the source.
+	DefaultTag string
+
+	// DefaultSeparator separates multiple default values for a MultiParser.
+	// When empty, a comma is used.
+	DefaultSeparator string
+
+	// PrefixTag is the tag to read a key prefix for inlined structs from (optional).
+	// The prefix applies to all nested keys.
+	PrefixTag string
+
+	// ValidateTag is the tag to read validation rules from (optional).
+	// Rules are run after a value has been assigned, see GetValidator.
+	ValidateTag string
+
+	// Known set of validators, in addition to the standard validators.
+	Validators map[string]Validator
+
 	// Use StrictTyping to prevent auto-conversion of returned values
 	StrictTyping bool
+
+	// When CollectErrors is set, continue processing fields after an error occurred.
+	// All errors are then returned together as an ErrCollected.
+	CollectErrors bool
+
+	// By default, a panic in a parser is recovered and returned as an ErrParserPanic.
+	// When RepanicParsers is set, the panic is propagated to the caller instead; this is useful for debugging.
+	RepanicParsers bool
 }
 
 // SingleParser is a function that parses a single value
@@ -46,27 +101,105 @@ var contextPool = &sync.Pool{
 // Data is unmarshaled from source to dest as follows:
 //
 // For each public field, the go tags are examined.
+// Unexported fields are skipped, as are fields whose name tag or parser tag is "-".
 //
 // When m.InlineParser is non-empty and m.ParserTag is non-empty and the parser tag equals the inline tag, atttempt
 // to recursivly calls UnmarshalState with the same source and data.
 // When the field type is a struct, the field value can be used as a new dest.
 // When the field type is a pointer to a struct, create a new zero value (when needed) for the provided type and then use it as a dest.
 // When the field type is none of the above, return ErrInlineNotStruct.
+// When m.PrefixTag is non-empty and the field has a non-empty prefix tag, all nested keys are prefixed with its value, see SourcePrefix.
+// Prefixes of nested inlined structs are combined.
+//
+// Embedded fields of struct or pointer-to-struct type are inlined automatically, even when m.InlineParser is empty.
+// This does not happen when the field has a parser tag, a name in the name tag, the "noinline" option, or parses itself.
+// Embedded pointers to unexported struct types are never inlined, as they can not be allocated.
+// The names of embedded fields are not part of the path passed to m.NameMapper, and their required fields are always enforced.
+// When several fields read the same key, the rules of encoding/json decide which one is used.
+// The field with the shallowest depth of embedding wins; at the same depth, a field with a name in the name tag wins.
+// When there is no unique winner, all of these fields are skipped.
+// Keys are compared including prefixes, but before m.NameMapper is applied.
+// Fields that are not within an embedded struct always read their key.
+// Structs inlined using m.InlineParser or a sub-source are treated like separate structs for this purpose.
+//
+// When m.SourceTag is non-empty and the field has a non-empty source tag, the field is read from the sub-source of that name instead.
+// The sub-source is found using the Select method of source, which must implement SourceSelector.
+// When the sub-source does not exist, an error is returned.
+// For inlined fields, the sub-source is used for all nested fields.
 //
 // When m.NameTag is non-empty, data from the specified name is read from source.
+// The name may be followed by comma-separated options, e.g. "name,required".
 // When m.NameTag does not exist, and m.StrictNameTag is true, the field is skipped.
 // When m.NameTag does not exist and m.StrictNameTag is false, data from the name of the field is read from source.
+// When additionally m.NameMapper is non-nil, it is used to map the field to the name instead.
+// It receives the names of all enclosing inlined fields as a path.
+//
+// When the "required" option is given, the key does not exist in source and there is no default value, an ErrMissingRequired is returned.
+// The parser is not called in this case.
+// The "required" option may also be given for inlined fields.
+// Required fields within an inlined struct are only enforced when the inlined field itself is required.
+//
+// When m.DefaultTag is non-empty, the field has a default tag, and the key does not exist in source, the value of the tag is used instead.
+// The parser is then called as if the key had existed.
+// For a MultiParser, the default value is split using m.DefaultSeparator; an empty default value results in an empty slice.
+// The Defaulted method of the UnmarshalContext indicates if a default value is being used.
 //
 // When m.ParserTag is non-empty, the value and ok are passed to the defined function in m.SingleParsers or m.MultiParsers.
 // When m.ParserTag is empty, and m.DefaultParser is non-empty, the value and ok are passed to the default function in m.SingleParsers or m.MultiParsers.
 // When m.ParserTag is empty, and m.DefaultParser is empty, or the referenced parser function does not exist, an error is returned.
 // When a parser exists in both m.SingleParsers and m.MultiParsers, an error is returned.
+// The parser may be followed by arguments in parentheses, e.g. "int(base=16,bits=8)" or "split(sep=;)".
+// Arguments are separated by commas; a backslash escapes the following character, e.g. "split(sep=\,)".
+// The arguments are available to the parser using the Args method of UnmarshalContext.
+// When the arguments are malformed, an ErrUnknownParser describing the problem is returned.
+//
+// Multiple parsers may be combined into a pipeline by separating them with "|", e.g. "trim|lower|int(base=16)".
+// The first stage determines if a single value or multiple values are read from source.
+// The output of each stage is passed to the next stage, and must be a string for a SingleParser or a []string for a MultiParser.
+// The Parser method of UnmarshalContext reports the entire pipeline, the Stage method reports the current stage.
+// When a stage fails, ErrFailedToParseField reports it using its Stage method.
 // When calling a parsing context, the ctx argument is passed to it unchanged.
 //
+// When the field has no parser tag, and a pointer to its type implements encoding.TextUnmarshaler, flag.Value or sql.Scanner,
+// that method is used to parse the value instead of m.DefaultParser, e.g. for net.IP or time.Time.
+// The same applies to fields of pointer type, in which case a new value is allocated.
+// The name of the interface is reported as the parser, see TextUnmarshalerParser, FlagValueParser and SQLScannerParser.
+// When the key does not exist in source and there is no default value, such fields are left unchanged.
+//
+// When the field has no parser tag, and m.TypeParsers or m.TypeMultiParsers contain a parser for the exact type of the field,
+// that parser is used instead of both the methods above and m.DefaultParser.
+// See GetFieldParser for the full order of precedence.
+//
 // When the Parser function returns a value and nil error, it is written into the specified field of dest.
 // When strict typing is disabled, will first attempt to convert the value to the target type.
 // When either the conversion, or assignablity is impossible, an error is returned.
+// When the Parser function panics, the panic is recovered and an ErrParserPanic is returned, unless m.RepanicParsers is set.
+//
+// When m.ValidateTag is non-empty and the field has a validate tag, its rules are run in order after the value has been assigned.
+// Rules are comma-separated and of the form "name" or "name=arg", e.g. "min=1,max=65535"; a literal comma in an argument is written as "\,".
+// Validators are found using GetValidator.
+// The first rule that fails, or that refers to an unknown validator, results in an ErrValidationFailed.
+//
+// The fields of each struct type, along with their names, tags and resolved parsers, are determined once and then cached.
+// Parsers are resolved again after a parser has been registered using one of the Register methods, or when the parser maps of m are replaced.
+// Parsers that are added to the maps directly, after m has been used, may therefore not be seen; use the Register methods instead.
+// Validators are looked up in their map whenever they are used, and m.NameMapper is called for every field on every call.
+//
+// By default, UnmarshalState returns the first error that occurs.
+// When m.CollectErrors is true, processing continues with the next field instead, and all errors are returned as an ErrCollected.
 func (m Marshal) UnmarshalState(dest interface{}, source Source, data ParsingData) error {
+	return m.UnmarshalContextual(context.Background(), dest, source, data)
+}
+
+// UnmarshalContextual is like UnmarshalState, but can be canceled using ctx.
+//
+// Ctx is available to parsers using the Context method of UnmarshalContext.
+// When source implements SourceContext, its context-aware methods are used to look up values.
+//
+// Before each field is processed, and after its value has been looked up and parsed, ctx is checked.
+// When ctx is done, processing stops immediately and an ErrCanceled recording the current field is returned.
+// This happens even when m.CollectErrors is true; errors collected until then are discarded.
+func (m Marshal) UnmarshalContextual(ctx context.Context, dest interface{}, source Source, data ParsingData) error {
 	if dest == nil {
 		return ErrDestIsNil
 	}
@@ -84,127 +217,258 @@ func (m Marshal) UnmarshalState(dest interface{}, source Source, data ParsingDat
 	}
 	dValue = dValue.Elem()
 
+	collector := &errCollector{collect: m.CollectErrors}
+	if err := m.unmarshalStruct(ctx, dValue, nil, nil, true, source, data, collector); err != nil {
+		return err
+	}
+	return collector.Err()
+}
+
+// unmarshalStruct unmarshals source into the struct dValue.
+// Path holds the names of the inlined fields containing dValue.
+// Shadow is the embedding of dValue; when nil, the embedding of its plan is used.
+// Required indicates if required fields of dValue must be present.
+// Errors are reported to collector; the first error that collector does not collect is returned.
+// When cctx is done, an ErrCanceled is returned without using collector.
+func (m Marshal) unmarshalStruct(cctx context.Context, dValue reflect.Value, path []string, shadow *embedding, required bool, source Source, data ParsingData, collector *errCollector) error {
+	plan := loadPlan(dValue.Type(), m.planConfig())
+	if shadow == nil {
+		shadow = plan.embedding
+	}
+
 	// grab a new context item from the pool
 	// and store context data with it.
 	ctx := contextPool.Get().(*unmarshalContext)
 	defer contextPool.Put(ctx)
 
 	ctx.data = data
+	ctx.ctx = cctx
 	defer ctx.Reset()
 
-	// Iterate over the values of that field
-	dNum := dType.NumField()
-	for i := 0; i < dNum; i++ {
-		fStructField := dType.Field(i)
-		fValue := dValue.Field(i)
-
-		fType := fStructField.Type
-		ctx.dest = fStructField.Name
-		ctx.tag = fStructField.Tag
-
-		// determine the type of parser to run
-		// using the default type when necessary
-		ctx.parser = fStructField.Tag.Get(m.ParserTag)
-		if ctx.parser == "" {
-			if m.DefaultParser == "" {
+	// the parsers of each field, resolved once for every set of parsers
+	parsers := m.resolvedParsers(plan)
+
+	// Iterate over the fields in the plan
+	for i := range plan.fields {
+		fp := &plan.fields[i]
+
+		// skip fields hidden by other fields of embedded structs
+		if shadow.isHidden(i) {
+			continue
+		}
+
+		fValue := dValue.Field(fp.index)
+
+		fType := fp.field.Type
+		ctx.dest = fp.field.Name
+		ctx.tag = fp.field.Tag
+		ctx.field = fp.field
+		ctx.parser = fp.parser
+		ctx.source = fp.source
+		if fp.mapped && m.NameMapper != nil {
+			ctx.source = m.NameMapper(fp.field, path)
+		}
+
+		// stop when we have been canceled
+		if err := cctx.Err(); err != nil {
+			return ErrCanceled{
+				dest:   ctx.dest,
+				source: ctx.source,
+				parser: ctx.parser,
+				tag:    ctx.tag,
+
+				cause: err,
+			}
+		}
+
+		// select the sub-source to read from
+		fSource := source
+		if fp.sub != "" {
+			selector, ok := source.(SourceSelector)
+			if ok {
+				fSource, ok = selector.Select(fp.sub)
+			}
+			if !ok {
+				if err := collector.Add(ErrUnknownSource{
+					dest:   ctx.dest,
+					source: ctx.source,
+					parser: ctx.parser,
+					tag:    ctx.tag,
+
+					Name: fp.sub,
+				}); err != nil {
+					return err
+				}
 				continue
 			}
-			ctx.parser = m.DefaultParser
 		}
 
 		// check if the inline parser is being requested.
 		// and if so, do the inlining.
-		if m.InlineParser != "" && ctx.parser == m.InlineParser {
-			var fieldPointer interface{}
-
-			switch fType.Kind() {
-			// it is a struct (without an indirection) => simple
-			case reflect.Struct:
-				fieldPointer = fValue.Addr().Interface()
-
-			// it should be a pointer to a struct
-			case reflect.Ptr:
-				// check that the pointed to element is indeed a struct
-				fType = fType.Elem()
-				if fType.Kind() != reflect.Struct {
-					return ErrInlineNotStruct{
-						dest:   ctx.dest,
-						parser: ctx.parser,
-						tag:    ctx.tag,
-					}
+		if fp.inline {
+			if fp.inlineErr {
+				if err := collector.Add(ErrInlineNotStruct{
+					dest:   ctx.dest,
+					parser: ctx.parser,
+					tag:    ctx.tag,
+				}); err != nil {
+					return err
 				}
+				continue
+			}
 
+			if fp.inlinePtr {
 				// when the value is nil, magically create a new value
 				// so that we can fill zeroed pointer types.
 				if fValue.IsNil() {
-					fValue.Set(reflect.New(fType))
-				}
-				// and use the fieldPointer
-				fieldPointer = fValue.Interface()
-			default:
-				return ErrInlineNotStruct{
-					dest:   ctx.dest,
-					tag:    ctx.tag,
-					parser: ctx.parser,
+					fValue.Set(reflect.New(fType.Elem()))
 				}
+				// and use the pointed to value
+				fValue = fValue.Elem()
 			}
 
-			err := m.UnmarshalState(fieldPointer, source, data)
-			if err != nil {
-				return err
+			if fp.prefix != "" {
+				fSource = SourcePrefix(fSource, fp.prefix)
 			}
-			continue
-		}
 
-		// determine which field to look at from the source
-		// use default when needed
-		ctx.source = fStructField.Tag.Get(m.NameTag)
-		if ctx.source == "" {
-			if m.StrictNameTag {
-				continue
+			// fields of embedded structs are promoted, so they are required like any other field
+			fRequired := required && (fp.required || fp.embedded)
+			if err := m.unmarshalStruct(cctx, fValue, m.innerPath(fp, path), shadow.child(i), fRequired, fSource, data, collector); err != nil {
+				return err
 			}
-			ctx.source = fStructField.Name
+			continue
 		}
 
 		// figure out if we have a single or a multi parser
-		singleParser, multiParser, err := m.GetParser(ctx.parser)
+		// a field without any parser is skipped
+		resolved := &parsers.fields[i]
+		rp, funcs, err := resolved.rp, resolved.funcs, resolved.err
+		if rp.name == "" {
+			continue
+		}
+		ctx.parser = rp.name
 		if err != nil {
-			return ErrUnknownParser{
+			if err := collector.Add(ErrUnknownParser{
 				dest:   ctx.dest,
 				source: ctx.source,
 				parser: ctx.parser,
 				tag:    ctx.tag,
 
 				cause: err,
+			}); err != nil {
+				return err
 			}
+			continue
 		}
 
-		// load and parse the appropriate value.
-		var pValue interface{}
-		var pErr error
+		// load the appropriate value.
+		// the first stage of the parser determines if a single or multiple values are read.
+		var lValue string
+		var lValues []string
+		var lOK, unchanged bool
 
-		switch {
-		case singleParser != nil:
-			rValue, rOK := source.Lookup(ctx.source)
+		if first := &funcs[0]; first.single != nil {
+			value, ok := lookupContext(cctx, fSource, ctx.source)
 			ctx.single = true
 
-			pValue, pErr = singleParser(rValue, rOK, ctx)
-		case multiParser != nil:
-			rValue, rOK := source.LookupAll(ctx.source)
+			ctx.defaulted = !ok && fp.hasDef
+			if ctx.defaulted {
+				value, ok = fp.def, true
+			}
+			lValue, lOK = value, ok
+
+			// fields that parse themselves are left unchanged when their value is missing.
+			unchanged = !ok && rp.self
+		} else {
+			value, ok := lookupAllContext(cctx, fSource, ctx.source)
 			ctx.single = false
 
-			pValue, pErr = multiParser(rValue, rOK, ctx)
+			ctx.defaulted = !ok && fp.hasDef
+			if ctx.defaulted {
+				value, ok = m.splitDefault(fp.def), true
+			}
+			lValues, lOK = value, ok
+		}
+		missing := !lOK && required && fp.required
+
+		// parse the value.
+		// when a required value is missing, the parser is not called.
+		var pValue interface{}
+		var pStage string
+		var pErr error
+		if !missing && !unchanged {
+			pValue, pStage, pErr = runPipeline(rp.stages, funcs, lValue, lValues, lOK, ctx, !m.RepanicParsers)
+		}
+
+		// the lookup or the parser may have been canceled
+		if err := cctx.Err(); err != nil {
+			return ErrCanceled{
+				dest:   ctx.dest,
+				source: ctx.source,
+				parser: ctx.parser,
+				single: ctx.single,
+				tag:    ctx.tag,
+
+				cause: err,
+			}
+		}
+
+		if missing {
+			if err := collector.Add(ErrMissingRequired{
+				dest:   ctx.dest,
+				source: ctx.source,
+				parser: ctx.parser,
+				single: ctx.single,
+				tag:    ctx.tag,
+			}); err != nil {
+				return err
+			}
+			continue
+		}
+		if unchanged {
+			continue
+		}
+		if panicked, ok := pErr.(*parserPanic); ok {
+			if err := collector.Add(ErrParserPanic{
+				dest:   ctx.dest,
+				source: ctx.source,
+				parser: ctx.parser,
+				single: ctx.single,
+				tag:    ctx.tag,
+
+				stage: pStage,
+
+				Value: panicked.value,
+				Stack: panicked.stack,
+			}); err != nil {
+				return err
+			}
+			continue
 		}
 		if pErr != nil {
-			return ErrFailedToParseField{
+			var pos Position
+			var hasPos bool
+			if positioner, ok := fSource.(SourcePositioner); ok {
+				pos, hasPos = positioner.Position(ctx.source)
+			}
+
+			if err := collector.Add(ErrFailedToParseField{
 				dest:   ctx.dest,
 				source: ctx.source,
 				parser: ctx.parser,
 				single: ctx.single,
 				tag:    ctx.tag,
 
+				stage: pStage,
+
+				pos:    pos,
+				hasPos: hasPos,
+
 				cause: pErr,
+			}); err != nil {
+				return err
 			}
+			continue
 		}
 
 		// we need to convert the value we received to the proper type.
@@ -215,7 +479,7 @@ func (m Marshal) UnmarshalState(dest interface{}, source Source, data ParsingDat
 				// when we allow automatic type conversions and we have a valid (non-nil) value returned
 				// convert the value to the proper type!
 				if !rValue.CanConvert(fType) {
-					return ErrWrongDestType{
+					if err := collector.Add(ErrWrongDestType{
 						dest:   ctx.dest,
 						source: ctx.source,
 						parser: ctx.parser,
@@ -227,11 +491,14 @@ func (m Marshal) UnmarshalState(dest interface{}, source Source, data ParsingDat
 						DestType:     fType,
 
 						cause: nil,
+					}); err != nil {
+						return err
 					}
+					continue
 				}
-				rValue, err = reflectConvert(rValue, fType)
+				cValue, err := reflectConvert(rValue, fType)
 				if err != nil {
-					return ErrWrongDestType{
+					if err := collector.Add(ErrWrongDestType{
 						dest:   ctx.dest,
 						source: ctx.source,
 						parser: ctx.parser,
@@ -243,21 +510,25 @@ func (m Marshal) UnmarshalState(dest interface{}, source Source, data ParsingDat
 						DestType:     fType,
 
 						cause: err,
+					}); err != nil {
+						return err
 					}
+					continue
 				}
+				rValue = cValue
 			} else {
 				// reflect.ValueOf(rValue) returned an invalid value.
 				// this can only happen when rValue is the zero value.
 				//
 				// so magically assume the zero-value of the desired type instead.
-				rValue = reflect.New(fType).Elem()
+				rValue = fp.zero
 			}
 		}
 
 		// safely assign the value to the proper type!
 		// we are already safe when we converterd
 		if m.StrictTyping && !rValue.Type().AssignableTo(fType) {
-			return ErrWrongDestType{
+			if err := collector.Add(ErrWrongDestType{
 				dest:   ctx.dest,
 				source: ctx.source,
 				parser: ctx.parser,
@@ -267,14 +538,75 @@ func (m Marshal) UnmarshalState(dest interface{}, source Source, data ParsingDat
 				Assignment:   true,
 				ReturnedType: rValue.Type(),
 				DestType:     fType,
+			}); err != nil {
+				return err
 			}
+			continue
 		}
 
 		fValue.Set(rValue)
+
+		if err := m.validateField(fValue, fp, fSource, ctx, collector); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateField runs the validation rules of fp against the value of fValue.
+// The first failing rule is added to collector.
+func (m Marshal) validateField(fValue reflect.Value, fp *fieldPlan, source Source, ctx *unmarshalContext, collector *errCollector) error {
+	if len(fp.rules) == 0 {
+		return nil
+	}
+
+	value := fValue.Interface()
+	for _, rule := range fp.rules {
+		validator, vErr := m.GetValidator(rule.name)
+		if vErr == nil {
+			vErr = validator(value, rule.arg, ctx)
+		}
+		if vErr == nil {
+			continue
+		}
+
+		var pos Position
+		var hasPos bool
+		if positioner, ok := source.(SourcePositioner); ok {
+			pos, hasPos = positioner.Position(ctx.source)
+		}
+
+		return collector.Add(ErrValidationFailed{
+			dest:   ctx.dest,
+			source: ctx.source,
+			parser: ctx.parser,
+			single: ctx.single,
+			tag:    ctx.tag,
+
+			pos:    pos,
+			hasPos: hasPos,
+
+			Rule: rule.name,
+
+			cause: vErr,
+		})
 	}
 	return nil
 }
 
+// splitDefault splits a default value for a MultiParser.
+func (m Marshal) splitDefault(def string) []string {
+	if def == "" {
+		return []string{}
+	}
+
+	sep := m.DefaultSeparator
+	if sep == "" {
+		sep = ","
+	}
+	return strings.Split(def, sep)
+}
+
 // Unmarshal is like UnmarshalState, but with a nil context
 func (m Marshal) Unmarshal(dest interface{}, source Source) error {
 	return m.UnmarshalState(dest, source, ParsingData{})
@@ -324,6 +656,190 @@ func (m Marshal) GetParser(name string) (single SingleParser, multi MultiParser,
 	return
 }
 
+// GetTypeParser finds either a single or multi parser for the type typ, and performs appropriate error checking
+func (m Marshal) GetTypeParser(typ reflect.Type) (single SingleParser, multi MultiParser, err error) {
+	var singleOK, multiOK bool
+
+	single, singleOK = m.TypeParsers[typ]
+	multi, multiOK = m.TypeMultiParsers[typ]
+
+	singleOK = singleOK && single != nil
+	multiOK = multiOK && multi != nil
+
+	if singleOK && multiOK {
+		return nil, nil, ErrBothParserType
+	}
+
+	if !(singleOK || multiOK) {
+		return nil, nil, ErrUnknownParserType
+	}
+
+	return
+}
+
+// EmbeddedParser is the name reported by GetFieldParser and UnmarshalContext.Parser for embedded structs that are inlined automatically,
+// when Marshal.InlineParser is empty.
+const EmbeddedParser = "embedded"
+
+// GetFieldParser finds the parser that UnmarshalState uses for field, and performs appropriate error checking.
+//
+// The parser is resolved in the following order:
+// the parser named by the parser tag, the type parser for the type of field, the methods of the field type (see UnmarshalState), and finally m.DefaultParser.
+//
+// Name is the name of the parser, as reported by UnmarshalContext.Parser.
+// For a type parser, this is the string representation of the type, e.g. "time.Duration".
+// When field is to be inlined, only name is returned.
+// For an embedded struct that is inlined automatically, name is m.InlineParser, or EmbeddedParser when m.InlineParser is empty.
+// When field is skipped, name is empty.
+func (m Marshal) GetFieldParser(field reflect.StructField) (name string, single SingleParser, multi MultiParser, err error) {
+	fp, ok := compileField(field, 0, m.planConfig())
+	if !ok {
+		return "", nil, nil, nil
+	}
+	if fp.inline {
+		return fp.parser, nil, nil, nil
+	}
+
+	rp, funcs, err := m.resolveParser(&fp)
+	if len(funcs) > 0 {
+		single, multi = funcs[0].single, funcs[0].multi
+	}
+	return rp.name, single, multi, err
+}
+
+// resolvedParser is the result of resolving the parser of a field.
+type resolvedParser struct {
+	name   string        // name of the parser, see UnmarshalContext.Parser
+	stages []parserStage // stages of the parser, a single stage when there is no pipeline
+
+	self bool // parser is a method of the field type
+}
+
+// parserFuncs holds the functions of a single stage of a parser.
+// Exactly one of them is non-nil.
+type parserFuncs struct {
+	single SingleParser
+	multi  MultiParser
+}
+
+// resolveParser resolves the parser of fp, see GetFieldParser.
+// When fp has no parser, the name of the result is empty.
+//
+// Funcs holds the functions of each stage.
+func (m Marshal) resolveParser(fp *fieldPlan) (rp resolvedParser, funcs []parserFuncs, err error) {
+	if !fp.tagged {
+		single, multi, err := m.GetTypeParser(fp.field.Type)
+		if err != ErrUnknownParserType {
+			rp.name = fp.typeStages[0].name
+			rp.stages = fp.typeStages
+			return rp, []parserFuncs{{single: single, multi: multi}}, err
+		}
+
+		if fp.self {
+			rp.name, rp.self = fp.parser, true
+			rp.stages = fp.stages
+			return rp, []parserFuncs{{single: selfParsers[fp.parser]}}, nil
+		}
+	}
+
+	rp.name = fp.parser
+	if rp.name == "" {
+		return rp, nil, nil
+	}
+	if fp.parserErr != nil {
+		return rp, nil, fp.parserErr
+	}
+
+	rp.stages, funcs = fp.stages, make([]parserFuncs, 0, len(fp.stages))
+	for _, stage := range fp.stages {
+		single, multi, err := m.GetParser(stage.name)
+		if err != nil {
+			if len(fp.stages) > 1 {
+				err = stageError{stage: stage.name, err: err}
+			}
+			return rp, nil, err
+		}
+		funcs = append(funcs, parserFuncs{single: single, multi: multi})
+	}
+	return rp, funcs, nil
+}
+
+// runPipeline runs the stages of a parser, using funcs as the functions of each stage.
+// The first stage is passed value when it is a SingleParser, and values when it is a MultiParser.
+// The output of each stage is passed to the next stage, and must be a string or []string as required by that stage.
+// Ok is passed unchanged to every stage.
+//
+// When a stage fails, returns the name of the stage along with the error.
+// When recoverPanics is set, a panic in a stage is recovered and returned as a *parserPanic.
+func runPipeline(stages []parserStage, funcs []parserFuncs, value string, values []string, ok bool, ctx *unmarshalContext, recoverPanics bool) (result interface{}, stage string, err error) {
+	if !recoverPanics {
+		return runStages(stages, funcs, value, values, ok, ctx)
+	}
+
+	// a panic is detected using completed rather than the result of recover.
+	// the latter is nil for panic(nil) under older language versions.
+	var completed bool
+	defer func() {
+		if completed {
+			return
+		}
+		r := recover()
+		result, stage, err = nil, ctx.stage, &parserPanic{value: r, stack: debug.Stack()}
+	}()
+
+	result, stage, err = runStages(stages, funcs, value, values, ok, ctx)
+	completed = true
+	return result, stage, err
+}
+
+// runStages implements runPipeline, without recovering panics.
+func runStages(stages []parserStage, funcs []parserFuncs, value string, values []string, ok bool, ctx *unmarshalContext) (result interface{}, stage string, err error) {
+	for i := range stages {
+		current, f := &stages[i], &funcs[i]
+		ctx.stage, ctx.args = current.name, current.args
+
+		if f.single != nil {
+			ctx.single = true
+
+			// the first stage receives the value directly, to avoid converting it to an interface
+			if i > 0 {
+				str, isString := result.(string)
+				if !isString {
+					return nil, stages[i-1].name, fmt.Errorf("returned %T, but the next stage %q requires a string", result, current.name)
+				}
+				value = str
+			}
+			result, err = f.single(value, ok, ctx)
+		} else {
+			ctx.single = false
+
+			if i > 0 {
+				strs, isStrings := result.([]string)
+				if !isStrings {
+					return nil, stages[i-1].name, fmt.Errorf("returned %T, but the next stage %q requires a []string", result, current.name)
+				}
+				values = strs
+			}
+			result, err = f.multi(values, ok, ctx)
+		}
+
+		if err != nil {
+			return nil, current.name, err
+		}
+	}
+	return result, "", nil
+}
+
+// parserPanic is returned by runPipeline when a stage panicked.
+type parserPanic struct {
+	value interface{}
+	stack []byte
+}
+
+func (p *parserPanic) Error() string {
+	return fmt.Sprintf("parser panicked: %v", p.value)
+}
+
 // RegisterSingleParser registers a new SingleParser with m.
 //
 // Parser should not be nil, and should not exist in m.MultiParsers.
@@ -333,6 +849,7 @@ func (m *Marshal) RegisterSingleParser(name string, parser SingleParser) {
 		m.SingleParsers = make(map[string]SingleParser)
 	}
 	m.SingleParsers[name] = parser
+	parsersChanged()
 }
 
 // RegisterSingleParser registers a new MultiParser with m.
@@ -344,4 +861,29 @@ func (m *Marshal) RegisterMultiParser(name string, parser MultiParser) {
 		m.MultiParsers = make(map[string]MultiParser)
 	}
 	m.MultiParsers[name] = parser
+	parsersChanged()
+}
+
+// RegisterTypeParser registers a new SingleParser for fields of type typ with m.
+//
+// Parser should not be nil, and typ should not exist in m.TypeMultiParsers.
+// No checking of these conditions is performed; they should be ensured by the caller.
+func (m *Marshal) RegisterTypeParser(typ reflect.Type, parser SingleParser) {
+	if m.TypeParsers == nil {
+		m.TypeParsers = make(map[reflect.Type]SingleParser)
+	}
+	m.TypeParsers[typ] = parser
+	parsersChanged()
+}
+
+// RegisterTypeMultiParser registers a new MultiParser for fields of type typ with m.
+//
+// Parser should not be nil, and typ should not exist in m.TypeParsers.
+// No checking of these conditions is performed; they should be ensured by the caller.
+func (m *Marshal) RegisterTypeMultiParser(typ reflect.Type, parser MultiParser) {
+	if m.TypeMultiParsers == nil {
+		m.TypeMultiParsers = make(map[reflect.Type]MultiParser)
+	}
+	m.TypeMultiParsers[typ] = parser
+	parsersChanged()
 }
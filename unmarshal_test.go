@@ -1,11 +1,14 @@
 package stringreader_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net"
 	"reflect"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/tkw1536/stringreader"
 )
@@ -286,3 +289,831 @@ func ExampleMarshal_UnmarshalSingle_recursive() {
 	// {pointed value}
 	// {preset value 3}
 }
+
+func TestMarshal_CollectErrors(t *testing.T) {
+	m := stringreader.Marshal{
+		ParserTag:     "parser",
+		DefaultParser: "string",
+		InlineParser:  "inline",
+		CollectErrors: true,
+	}
+	m.RegisterSingleParser("string", func(value string, ok bool, ctx stringreader.UnmarshalContext) (interface{}, error) {
+		return value, nil
+	})
+	m.RegisterSingleParser("never", func(value string, ok bool, ctx stringreader.UnmarshalContext) (interface{}, error) {
+		return nil, errors.New("never parser")
+	})
+
+	type Nested struct {
+		Broken string `parser:"never"`
+	}
+
+	var dest struct {
+		First   string `parser:"never"`
+		Unknown string `parser:"unknown"`
+		Good    string
+		Inline  Nested `parser:"inline"`
+		Invalid int    `parser:"inline"`
+	}
+
+	err := m.UnmarshalSingle(&dest, stringreader.SourceSingleMap{
+		"Good": "good",
+	})
+
+	var collected stringreader.ErrCollected
+	if !errors.As(err, &collected) {
+		t.Fatalf("Marshal.UnmarshalSingle() err = %v, want ErrCollected", err)
+	}
+
+	gotDests := make([]string, len(collected.Errors))
+	for i, e := range collected.Errors {
+		gotDests[i] = e.Dest()
+	}
+	wantDests := []string{"First", "Unknown", "Broken", "Invalid"}
+	if !reflect.DeepEqual(gotDests, wantDests) {
+		t.Errorf("ErrCollected.Errors dests = %v, want = %v", gotDests, wantDests)
+	}
+
+	if dest.Good != "good" {
+		t.Errorf("Marshal.UnmarshalSingle() did not continue after errors")
+	}
+
+	if !errors.Is(err, stringreader.ErrUnknownParserType) {
+		t.Errorf("errors.Is(err, ErrUnknownParserType) = false, want = true")
+	}
+
+	var inlineErr stringreader.ErrInlineNotStruct
+	if !errors.As(err, &inlineErr) || inlineErr.Dest() != "Invalid" {
+		t.Errorf("errors.As(err, ErrInlineNotStruct) did not find inline error")
+	}
+}
+
+func ExampleMarshal_UnmarshalSingle_prefix() {
+	var marshal stringreader.Marshal
+	marshal.NameTag = "read"
+	marshal.ParserTag = "type"
+	marshal.InlineParser = "inline"
+	marshal.PrefixTag = "prefix"
+	marshal.RegisterStandardParsers()
+
+	type Endpoint struct {
+		Host string `read:"host"`
+		Port uint16 `read:"port"`
+	}
+
+	// inline the same struct twice, with a different prefix each
+	type Config struct {
+		Primary  Endpoint  `type:"inline" prefix:"primary."`
+		Fallback *Endpoint `type:"inline" prefix:"fallback."`
+	}
+
+	var config Config
+	err := marshal.UnmarshalSingle(&config, stringreader.SourceSingleMap{
+		"primary.host":  "primary.example.com",
+		"primary.port":  "80",
+		"fallback.host": "fallback.example.com",
+		"fallback.port": "8080",
+	})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("%v\n", config.Primary)
+	fmt.Printf("%v\n", *config.Fallback)
+
+	// Output:
+	// {primary.example.com 80}
+	// {fallback.example.com 8080}
+}
+
+func ExampleMarshal_Unmarshal_defaults() {
+	var marshal stringreader.Marshal
+	marshal.NameTag = "read"
+	marshal.ParserTag = "type"
+	marshal.DefaultTag = "default"
+	marshal.RegisterStandardParsers()
+
+	// report when a default value is used
+	marshal.RegisterSingleParser("port", func(value string, ok bool, ctx stringreader.UnmarshalContext) (interface{}, error) {
+		if ctx.Defaulted() {
+			fmt.Printf("using default port %s\n", value)
+		}
+		return strconv.ParseUint(value, 10, 16)
+	})
+
+	// a multi parser that returns the list of values
+	marshal.RegisterMultiParser("list", func(value []string, ok bool, ctx stringreader.UnmarshalContext) (interface{}, error) {
+		return value, nil
+	})
+
+	type Config struct {
+		Host  string   `read:"host" default:"localhost"`
+		Port  uint16   `read:"port" type:"port" default:"8080"`
+		Tags  []string `read:"tags" type:"list" default:"a,b,c"`
+		Empty []string `read:"empty" type:"list" default:""`
+	}
+
+	var config Config
+	err := marshal.Unmarshal(&config, stringreader.SourceSmartSplit{
+		SourceMulti: stringreader.SourceMultiMap{
+			"host": {"example.com"},
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("%+v\n", config)
+
+	// Output:
+	// using default port 8080
+	// {Host:example.com Port:8080 Tags:[a b c] Empty:[]}
+}
+
+func TestMarshal_Unmarshal_required(t *testing.T) {
+	var m stringreader.Marshal
+	m.NameTag = "read"
+	m.ParserTag = "type"
+	m.InlineParser = "inline"
+	m.DefaultTag = "default"
+	m.PrefixTag = "prefix"
+	m.CollectErrors = true
+	m.RegisterStandardParsers()
+
+	type Endpoint struct {
+		Host string `read:"host,required"`
+	}
+
+	var dest struct {
+		Name      string    `read:"name,required"`
+		Port      int       `read:"port,required" default:"80"`
+		Optional  string    `read:",required" default:""`
+		Required  Endpoint  `read:",required" type:"inline" prefix:"required."`
+		Unchecked *Endpoint `type:"inline"`
+	}
+
+	err := m.Unmarshal(&dest, stringreader.SourceSplit{})
+
+	var collected stringreader.ErrCollected
+	if !errors.As(err, &collected) {
+		t.Fatalf("Marshal.Unmarshal() err = %v, want ErrCollected", err)
+	}
+
+	var got []string
+	for _, e := range collected.Errors {
+		if _, ok := e.(stringreader.ErrMissingRequired); !ok {
+			t.Errorf("Marshal.Unmarshal() returned unexpected error %v", e)
+			continue
+		}
+		got = append(got, e.Source())
+	}
+	want := []string{"name", "host"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Marshal.Unmarshal() missing keys = %v, want = %v", got, want)
+	}
+}
+
+func TestMarshal_GetFieldParser(t *testing.T) {
+	var m stringreader.Marshal
+	m.ParserTag = "type"
+	m.InlineParser = "inline"
+	m.RegisterStandardParsers()
+
+	type Nested struct{}
+	type Fields struct {
+		Tagged   time.Duration `type:"int64"`
+		Typed    time.Duration
+		Self     net.IP
+		Default  int
+		Inline   Nested `type:"inline"`
+		Override net.IP
+		Nested
+	}
+
+	tests := []struct {
+		field      string
+		wantName   string
+		wantParser bool
+	}{
+		{"Tagged", "int64", true},
+		{"Typed", "time.Duration", true},
+		{"Self", stringreader.TextUnmarshalerParser, true},
+		{"Default", stringreader.StandardDefaultParser, true},
+		{"Inline", "inline", false},
+		{"Nested", "inline", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			field, _ := reflect.TypeOf(Fields{}).FieldByName(tt.field)
+
+			gotName, gotSingle, gotMulti, err := m.GetFieldParser(field)
+			if err != nil {
+				t.Fatalf("Marshal.GetFieldParser() err = %v", err)
+			}
+			if gotName != tt.wantName {
+				t.Errorf("Marshal.GetFieldParser() name = %q, want = %q", gotName, tt.wantName)
+			}
+			if gotParser := gotSingle != nil || gotMulti != nil; gotParser != tt.wantParser {
+				t.Errorf("Marshal.GetFieldParser() has parser = %t, want = %t", gotParser, tt.wantParser)
+			}
+		})
+	}
+
+	// without an inline parser, embedded structs can still be told apart from skipped fields
+	m2 := m
+	m2.InlineParser = ""
+	embedded, _ := reflect.TypeOf(Fields{}).FieldByName("Nested")
+	if name, _, _, _ := m2.GetFieldParser(embedded); name != stringreader.EmbeddedParser {
+		t.Errorf("Marshal.GetFieldParser() name = %q, want = %q", name, stringreader.EmbeddedParser)
+	}
+
+	// a type parser takes precedence over the methods of a type
+	m.RegisterTypeParser(reflect.TypeOf(net.IP{}), func(value string, ok bool, ctx stringreader.UnmarshalContext) (interface{}, error) {
+		return net.IPv4(127, 0, 0, 1), nil
+	})
+	field, _ := reflect.TypeOf(Fields{}).FieldByName("Override")
+	if name, _, _, _ := m.GetFieldParser(field); name != "net.IP" {
+		t.Errorf("Marshal.GetFieldParser() name = %q, want = %q", name, "net.IP")
+	}
+}
+
+// contextSource is a SourceContext that records the context values it was called with.
+type contextSource struct {
+	stringreader.SourceSmartSplit
+	seen []interface{}
+}
+
+type contextKey struct{}
+
+func (s *contextSource) LookupContext(ctx context.Context, key string) (string, bool) {
+	s.seen = append(s.seen, ctx.Value(contextKey{}))
+	return s.Lookup(key)
+}
+
+func (s *contextSource) LookupAllContext(ctx context.Context, key string) ([]string, bool) {
+	s.seen = append(s.seen, ctx.Value(contextKey{}))
+	return s.LookupAll(key)
+}
+
+func TestMarshal_UnmarshalContextual(t *testing.T) {
+	var m stringreader.Marshal
+	m.ParserTag = "type"
+	m.CollectErrors = true
+
+	var cancel context.CancelFunc
+	var parsed []string
+	m.RegisterSingleParser("record", func(value string, ok bool, ctx stringreader.UnmarshalContext) (interface{}, error) {
+		if got := ctx.Context().Value(contextKey{}); got != "value" {
+			t.Errorf("UnmarshalContext.Context() value = %v, want = %q", got, "value")
+		}
+		parsed = append(parsed, ctx.Dest())
+		if ctx.Dest() == "B" {
+			cancel()
+		}
+		return value, nil
+	})
+
+	type Dest struct {
+		A string `type:"record"`
+		B string `type:"record"`
+		C string `type:"record"`
+	}
+
+	t.Run("canceled while parsing", func(t *testing.T) {
+		parsed = nil
+
+		ctx, c := context.WithCancel(context.WithValue(context.Background(), contextKey{}, "value"))
+		defer c()
+		cancel = c
+
+		source := &contextSource{SourceSmartSplit: stringreader.SourceSmartSplit{
+			SourceSingle: stringreader.SourceSingleMap{"A": "a", "B": "b", "C": "c"},
+		}}
+
+		var dest Dest
+		err := m.UnmarshalContextual(ctx, &dest, source, stringreader.ParsingData{})
+
+		var cErr stringreader.ErrCanceled
+		if !errors.As(err, &cErr) || cErr.Dest() != "B" {
+			t.Fatalf("Marshal.UnmarshalContextual() err = %v, want ErrCanceled for B", err)
+		}
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Marshal.UnmarshalContextual() err = %v, want context.Canceled", err)
+		}
+		if want := []string{"A", "B"}; !reflect.DeepEqual(parsed, want) {
+			t.Errorf("Marshal.UnmarshalContextual() parsed = %v, want = %v", parsed, want)
+		}
+		if want := []interface{}{"value", "value"}; !reflect.DeepEqual(source.seen, want) {
+			t.Errorf("SourceContext.LookupContext() saw = %v, want = %v", source.seen, want)
+		}
+	})
+
+	t.Run("canceled before start", func(t *testing.T) {
+		parsed = nil
+
+		ctx, c := context.WithCancel(context.Background())
+		c()
+
+		var dest Dest
+		err := m.UnmarshalContextual(ctx, &dest, stringreader.SourceSplit{}, stringreader.ParsingData{})
+
+		var cErr stringreader.ErrCanceled
+		if !errors.As(err, &cErr) || cErr.Dest() != "A" {
+			t.Fatalf("Marshal.UnmarshalContextual() err = %v, want ErrCanceled for A", err)
+		}
+		if len(parsed) != 0 {
+			t.Errorf("Marshal.UnmarshalContextual() parsed = %v, want none", parsed)
+		}
+	})
+}
+
+func TestMarshal_Unmarshal_parserArgs(t *testing.T) {
+	var m stringreader.Marshal
+	m.ParserTag = "type"
+	m.RegisterSingleParser("args", func(value string, ok bool, ctx stringreader.UnmarshalContext) (interface{}, error) {
+		return fmt.Sprintf("%s %v", ctx.Parser(), ctx.Args()), nil
+	})
+
+	t.Run("args", func(t *testing.T) {
+		var dest struct {
+			With    string `type:"args(a=1,b=2)"`
+			Without string `type:"args"`
+		}
+		if err := m.UnmarshalSingle(&dest, stringreader.SourceSingleMap{}); err != nil {
+			t.Fatalf("Marshal.UnmarshalSingle() err = %v", err)
+		}
+		if want := "args map[a:1 b:2]"; dest.With != want {
+			t.Errorf("Marshal.UnmarshalSingle() With = %q, want = %q", dest.With, want)
+		}
+		if want := "args map[]"; dest.Without != want {
+			t.Errorf("Marshal.UnmarshalSingle() Without = %q, want = %q", dest.Without, want)
+		}
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		var dest struct {
+			Field string `type:"args(a=1"`
+		}
+		err := m.UnmarshalSingle(&dest, stringreader.SourceSingleMap{})
+
+		var pErr stringreader.ErrUnknownParser
+		if !errors.As(err, &pErr) {
+			t.Fatalf("Marshal.UnmarshalSingle() err = %v, want ErrUnknownParser", err)
+		}
+		if want := `Marshal.Unmarshal: Destination field "Field" has unknown parser args(a=1: missing ")" at end of arguments of parser "args"`; err.Error() != want {
+			t.Errorf("Marshal.UnmarshalSingle() err = %q, want = %q", err.Error(), want)
+		}
+	})
+}
+
+func ExampleMarshal_UnmarshalSingle_pipeline() {
+	var m stringreader.Marshal
+	m.ParserTag = "type"
+	m.RegisterStandardParsers()
+
+	var dest struct {
+		Level string `type:"trim|lower|oneof(values=debug info warn)"`
+		Mask  uint8  `type:"trim|uint8(base=16)"`
+	}
+
+	err := m.UnmarshalSingle(&dest, stringreader.SourceSingleMap{
+		"Level": "  INFO ",
+		"Mask":  " ff ",
+	})
+	fmt.Println(err, dest)
+
+	err = m.UnmarshalSingle(&dest, stringreader.SourceSingleMap{"Level": "Trace"})
+	fmt.Println(err)
+
+	// Output:
+	// <nil> {info 255}
+	// Marshal.Unmarshal: Failed to parse field "Level" in stage "oneof": value "trace" is not one of "debug info warn"
+}
+
+func TestMarshal_Unmarshal_pipeline(t *testing.T) {
+	var m stringreader.Marshal
+	m.ParserTag = "type"
+	m.RegisterStandardParsers()
+
+	var stages []string
+	m.RegisterSingleParser("record", func(value string, ok bool, ctx stringreader.UnmarshalContext) (interface{}, error) {
+		stages = append(stages, ctx.Parser()+"/"+ctx.Stage())
+		return value, nil
+	})
+
+	t.Run("stages", func(t *testing.T) {
+		stages = nil
+
+		var dest struct {
+			Field string `type:"record|trim|record"`
+		}
+		if err := m.UnmarshalSingle(&dest, stringreader.SourceSingleMap{"Field": " x "}); err != nil {
+			t.Fatalf("Marshal.UnmarshalSingle() err = %v", err)
+		}
+		if dest.Field != "x" {
+			t.Errorf("Marshal.UnmarshalSingle() Field = %q, want = %q", dest.Field, "x")
+		}
+		if want := []string{"record|trim|record/record", "record|trim|record/record"}; !reflect.DeepEqual(stages, want) {
+			t.Errorf("UnmarshalContext.Parser()/Stage() = %v, want = %v", stages, want)
+		}
+	})
+
+	t.Run("non-string output", func(t *testing.T) {
+		var dest struct {
+			Field string `type:"int|trim"`
+		}
+		err := m.UnmarshalSingle(&dest, stringreader.SourceSingleMap{"Field": "42"})
+
+		var fErr stringreader.ErrFailedToParseField
+		if !errors.As(err, &fErr) || fErr.Stage() != "int" || fErr.Parser() != "int|trim" {
+			t.Fatalf("Marshal.UnmarshalSingle() err = %v, want ErrFailedToParseField in stage int", err)
+		}
+		if want := `Marshal.Unmarshal: Failed to parse field "Field" in stage "int": returned int, but the next stage "trim" requires a string`; err.Error() != want {
+			t.Errorf("Marshal.UnmarshalSingle() err = %q, want = %q", err.Error(), want)
+		}
+	})
+
+	t.Run("unknown stage", func(t *testing.T) {
+		var dest struct {
+			Field string `type:"trim|fake"`
+		}
+		err := m.UnmarshalSingle(&dest, stringreader.SourceSingleMap{"Field": "42"})
+
+		var pErr stringreader.ErrUnknownParser
+		if !errors.As(err, &pErr) || !errors.Is(err, stringreader.ErrUnknownParserType) {
+			t.Fatalf("Marshal.UnmarshalSingle() err = %v, want ErrUnknownParser", err)
+		}
+		if want := `Marshal.Unmarshal: Destination field "Field" has unknown parser trim|fake: stage "fake": Marshal.Unmarshal: unknown parser type`; err.Error() != want {
+			t.Errorf("Marshal.UnmarshalSingle() err = %q, want = %q", err.Error(), want)
+		}
+	})
+
+	t.Run("marshal uses last stage", func(t *testing.T) {
+		var src struct {
+			Mask uint8 `type:"trim|uint8(base=16)"`
+		}
+		src.Mask = 255
+
+		single, _, err := m.Marshal(src)
+		if err != nil {
+			t.Fatalf("Marshal.Marshal() err = %v", err)
+		}
+		if single["Mask"] != "ff" {
+			t.Errorf("Marshal.Marshal() Mask = %q, want = %q", single["Mask"], "ff")
+		}
+	})
+}
+
+func ExampleMarshal_UnmarshalSingle_embedded() {
+	var marshal stringreader.Marshal
+	marshal.NameTag = "read"
+	marshal.ParserTag = "type"
+	marshal.RegisterStandardParsers()
+
+	type Endpoint struct {
+		Host string `read:"host"`
+		Port uint16 `read:"port"`
+	}
+
+	type Credentials struct {
+		User string `read:"user"`
+	}
+
+	// embedded structs are inlined without an inline parser.
+	// Host hides the field of the same name in Endpoint.
+	type Config struct {
+		Endpoint
+		*Credentials
+		Host string `read:"host"`
+	}
+
+	var config Config
+	err := marshal.UnmarshalSingle(&config, stringreader.SourceSingleMap{
+		"host": "example.com",
+		"port": "80",
+		"user": "admin",
+	})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("%q %q %d %q\n", config.Host, config.Endpoint.Host, config.Port, config.User)
+
+	// Output:
+	// "example.com" "" 80 "admin"
+}
+
+func TestMarshal_Unmarshal_embedded(t *testing.T) {
+	var m stringreader.Marshal
+	m.NameTag = "read"
+	m.ParserTag = "type"
+	m.PrefixTag = "prefix"
+	m.CollectErrors = true
+	m.RegisterStandardParsers()
+
+	type Base struct {
+		Name string `read:"name,required"`
+		Port int    `read:"port"`
+	}
+
+	type Timestamp struct {
+		Created string `read:"created"`
+	}
+
+	t.Run("shadowing and required", func(t *testing.T) {
+		type Middle struct {
+			Base
+			Port int `read:"port"`
+		}
+		var dest struct {
+			Middle
+			Name string `read:"name"`
+		}
+		err := m.UnmarshalSingle(&dest, stringreader.SourceSingleMap{"name": "outer", "port": "1"})
+		if err != nil {
+			t.Fatalf("Marshal.UnmarshalSingle() err = %v", err)
+		}
+		if dest.Name != "outer" || dest.Middle.Base.Name != "" || dest.Middle.Port != 1 || dest.Middle.Base.Port != 0 {
+			t.Errorf("Marshal.UnmarshalSingle() = %+v", dest)
+		}
+
+		var missing struct{ Base }
+		err = m.UnmarshalSingle(&missing, stringreader.SourceSingleMap{})
+		var mErr stringreader.ErrMissingRequired
+		if !errors.As(err, &mErr) || mErr.Source() != "name" {
+			t.Errorf("Marshal.UnmarshalSingle() err = %v, want ErrMissingRequired for %q", err, "name")
+		}
+	})
+
+	t.Run("prefixed", func(t *testing.T) {
+		// the prefixed key "base.port" is not hidden by "port"
+		var dest struct {
+			Base `prefix:"base."`
+			Port int `read:"port"`
+		}
+		err := m.UnmarshalSingle(&dest, stringreader.SourceSingleMap{"base.name": "inner", "base.port": "2", "port": "1"})
+		if err != nil {
+			t.Fatalf("Marshal.UnmarshalSingle() err = %v", err)
+		}
+		if dest.Name != "inner" || dest.Base.Port != 2 || dest.Port != 1 {
+			t.Errorf("Marshal.UnmarshalSingle() = %+v", dest)
+		}
+	})
+
+	t.Run("opt out", func(t *testing.T) {
+		var dest struct {
+			Timestamp `read:",noinline"`
+			Base      `read:"base"`
+		}
+		if err := m.UnmarshalSingle(&dest, stringreader.SourceSingleMap{"created": "now", "name": "inner"}); err != nil {
+			t.Fatalf("Marshal.UnmarshalSingle() err = %v", err)
+		}
+		if dest.Created != "" || dest.Name != "" {
+			t.Errorf("Marshal.UnmarshalSingle() inlined a field that opted out: %+v", dest)
+		}
+	})
+
+	t.Run("depth precedence", func(t *testing.T) {
+		type A struct{ X string }
+		type D struct{ X string }
+		type C struct{ D }
+		type Deep struct {
+			A
+			C
+		}
+
+		var dest Deep
+		if err := m.UnmarshalSingle(&dest, stringreader.SourceSingleMap{"X": "x"}); err != nil {
+			t.Fatalf("Marshal.UnmarshalSingle() err = %v", err)
+		}
+		if dest.A.X != "x" || dest.C.D.X != "" {
+			t.Errorf("Marshal.UnmarshalSingle() = %+v, want only A.X to be set", dest)
+		}
+
+		single, _, err := m.Marshal(Deep{A: A{X: "a"}, C: C{D: D{X: "d"}}})
+		if err != nil {
+			t.Fatalf("Marshal.Marshal() err = %v", err)
+		}
+		want := stringreader.SourceSingleMap{"X": "a"}
+		if !reflect.DeepEqual(single, want) {
+			t.Errorf("Marshal.Marshal() = %v, want = %v", single, want)
+		}
+	})
+
+	t.Run("same depth", func(t *testing.T) {
+		type A struct {
+			X string
+			Y string `read:"Y"`
+		}
+		type B struct {
+			X string
+			Y string
+		}
+		type Same struct {
+			A
+			B
+		}
+
+		// X is ambiguous and dropped, the tagged Y of A wins
+		var dest Same
+		if err := m.UnmarshalSingle(&dest, stringreader.SourceSingleMap{"X": "x", "Y": "y"}); err != nil {
+			t.Fatalf("Marshal.UnmarshalSingle() err = %v", err)
+		}
+		want := Same{A: A{Y: "y"}}
+		if dest != want {
+			t.Errorf("Marshal.UnmarshalSingle() = %+v, want = %+v", dest, want)
+		}
+
+		single, _, err := m.Marshal(Same{A: A{X: "a", Y: "a"}, B: B{X: "b", Y: "b"}})
+		if err != nil {
+			t.Fatalf("Marshal.Marshal() err = %v", err)
+		}
+		wantSingle := stringreader.SourceSingleMap{"Y": "a"}
+		if !reflect.DeepEqual(single, wantSingle) {
+			t.Errorf("Marshal.Marshal() = %v, want = %v", single, wantSingle)
+		}
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		type Node struct {
+			*Node
+			Value string `read:"value"`
+		}
+
+		var dest Node
+		if err := m.UnmarshalSingle(&dest, stringreader.SourceSingleMap{"value": "v"}); err != nil {
+			t.Fatalf("Marshal.UnmarshalSingle() err = %v", err)
+		}
+		if dest.Value != "v" || dest.Node != nil {
+			t.Errorf("Marshal.UnmarshalSingle() = %+v", dest)
+		}
+	})
+
+	t.Run("marshal", func(t *testing.T) {
+		type Config struct {
+			Base
+			*Timestamp
+			Name string `read:"name"`
+		}
+		single, _, err := m.Marshal(Config{Base: Base{Name: "inner", Port: 80}, Name: "outer"})
+		if err != nil {
+			t.Fatalf("Marshal.Marshal() err = %v", err)
+		}
+		want := stringreader.SourceSingleMap{"name": "outer", "port": "80"}
+		if !reflect.DeepEqual(single, want) {
+			t.Errorf("Marshal.Marshal() = %v, want = %v", single, want)
+		}
+	})
+}
+
+func TestMarshal_Unmarshal_unexported(t *testing.T) {
+	var m stringreader.Marshal
+	m.NameTag = "read"
+	m.ParserTag = "type"
+	m.RegisterStandardParsers()
+
+	type state struct {
+		Count int `read:"count"`
+		cache map[string]string
+	}
+
+	type Config struct {
+		state
+		Name    string `read:"name"`
+		Ignored string `read:"-"`
+		Skipped string `type:"-"`
+		secret  string
+		mu      *struct{ locked bool }
+	}
+
+	source := stringreader.SourceSingleMap{
+		"name":    "example",
+		"count":   "3",
+		"Ignored": "x",
+		"-":       "x",
+		"Skipped": "x",
+		"secret":  "x",
+		"cache":   "x",
+		"mu":      "x",
+	}
+
+	var config Config
+	if err := m.UnmarshalSingle(&config, source); err != nil {
+		t.Fatalf("Marshal.UnmarshalSingle() err = %v", err)
+	}
+	want := Config{state: state{Count: 3}, Name: "example"}
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("Marshal.UnmarshalSingle() = %+v, want = %+v", config, want)
+	}
+
+	single, _, err := m.Marshal(config)
+	if err != nil {
+		t.Fatalf("Marshal.Marshal() err = %v", err)
+	}
+	wantSingle := stringreader.SourceSingleMap{"name": "example", "count": "3"}
+	if !reflect.DeepEqual(single, wantSingle) {
+		t.Errorf("Marshal.Marshal() = %v, want = %v", single, wantSingle)
+	}
+
+	name, _, _, err := m.GetFieldParser(reflect.TypeOf(config).Field(2))
+	if name != "" || err != nil {
+		t.Errorf("Marshal.GetFieldParser() = %q, %v, want a skipped field", name, err)
+	}
+}
+
+func TestMarshal_Unmarshal_parserPanic(t *testing.T) {
+	var m stringreader.Marshal
+	m.ParserTag = "type"
+	m.RegisterStandardParsers()
+	m.RegisterSingleParser("explode", func(value string, ok bool, ctx stringreader.UnmarshalContext) (interface{}, error) {
+		panic("boom")
+	})
+
+	var dest struct {
+		Value string `type:"trim|explode"`
+	}
+	source := stringreader.SourceSingleMap{"Value": " hello "}
+
+	t.Run("recovered", func(t *testing.T) {
+		err := m.UnmarshalSingle(&dest, source)
+
+		var pErr stringreader.ErrParserPanic
+		if !errors.As(err, &pErr) {
+			t.Fatalf("Marshal.UnmarshalSingle() err = %v, want ErrParserPanic", err)
+		}
+		if pErr.Value != "boom" || pErr.Dest() != "Value" || pErr.Parser() != "trim|explode" || pErr.Stage() != "explode" {
+			t.Errorf("Marshal.UnmarshalSingle() err = %#v", pErr)
+		}
+		if len(pErr.Stack) == 0 {
+			t.Error("Marshal.UnmarshalSingle() err has no stack trace")
+		}
+		want := `Marshal.Unmarshal: Parser panicked while parsing field "Value" in stage "explode": boom`
+		if err.Error() != want {
+			t.Errorf("Marshal.UnmarshalSingle() err = %q, want = %q", err.Error(), want)
+		}
+	})
+
+	t.Run("nil panic", func(t *testing.T) {
+		m2 := m
+		m2.RegisterSingleParser("nil", func(value string, ok bool, ctx stringreader.UnmarshalContext) (interface{}, error) {
+			panic(nil)
+		})
+
+		dest2 := struct {
+			Value string `type:"nil"`
+		}{Value: "pre"}
+		err := m2.UnmarshalSingle(&dest2, source)
+
+		var pErr stringreader.ErrParserPanic
+		if !errors.As(err, &pErr) {
+			t.Fatalf("Marshal.UnmarshalSingle() err = %v, want ErrParserPanic", err)
+		}
+		if dest2.Value != "pre" {
+			t.Errorf("Marshal.UnmarshalSingle() Value = %q, want = %q", dest2.Value, "pre")
+		}
+	})
+
+	t.Run("collected", func(t *testing.T) {
+		m2 := m
+		m2.CollectErrors = true
+
+		var dest2 struct {
+			First  string `type:"explode"`
+			Second string `type:"trim"`
+		}
+		err := m2.UnmarshalSingle(&dest2, stringreader.SourceSingleMap{"First": "x", "Second": " y "})
+
+		var collected stringreader.ErrCollected
+		if !errors.As(err, &collected) || len(collected.Errors) != 1 {
+			t.Fatalf("Marshal.UnmarshalSingle() err = %v, want a single collected error", err)
+		}
+		if dest2.Second != "y" {
+			t.Errorf("Marshal.UnmarshalSingle() did not continue after a panic: %+v", dest2)
+		}
+	})
+
+	t.Run("repanic", func(t *testing.T) {
+		m2 := m
+		m2.RepanicParsers = true
+
+		func() {
+			defer func() {
+				if r := recover(); r != "boom" {
+					t.Errorf("Marshal.UnmarshalSingle() recovered %v, want = %q", r, "boom")
+				}
+			}()
+			m2.UnmarshalSingle(&dest, source)
+		}()
+
+		// the context is reset, even after a panic
+		m2.RegisterSingleParser("check", func(value string, ok bool, ctx stringreader.UnmarshalContext) (interface{}, error) {
+			if ctx.Args() != nil || ctx.Stage() != "check" {
+				return nil, fmt.Errorf("context was not reset: args = %v, stage = %q", ctx.Args(), ctx.Stage())
+			}
+			return value, nil
+		})
+		var dest2 struct {
+			Value string `type:"check"`
+		}
+		if err := m2.UnmarshalSingle(&dest2, source); err != nil {
+			t.Errorf("Marshal.UnmarshalSingle() err = %v", err)
+		}
+	})
+}
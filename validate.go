@@ -0,0 +1,267 @@
+package stringreader
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Validator is a function that validates a value after it has been assigned to a field.
+// Value is the new value of the field, arg is the argument of the rule, or the empty string if there is none.
+//
+// When value is valid, a Validator should return nil.
+// Otherwise, it should return an error describing why value is invalid.
+type Validator = func(value interface{}, arg string, ctx UnmarshalContext) error
+
+// validationRule is a single rule in a validation tag.
+type validationRule struct {
+	name, arg string
+}
+
+// parseValidateTag parses a validate tag into a list of rules.
+// Rules are separated by commas, each rule is either of the form "name" or "name=arg".
+// A comma within an argument can be escaped using a backslash.
+func parseValidateTag(tag string) (rules []validationRule) {
+	if tag == "" {
+		return nil
+	}
+
+	var current strings.Builder
+	flush := func() {
+		rule := current.String()
+		current.Reset()
+
+		if rule == "" {
+			return
+		}
+		if eq := strings.IndexByte(rule, '='); eq >= 0 {
+			rules = append(rules, validationRule{name: rule[:eq], arg: rule[eq+1:]})
+			return
+		}
+		rules = append(rules, validationRule{name: rule})
+	}
+
+	for i := 0; i < len(tag); i++ {
+		switch {
+		case tag[i] == '\\' && i+1 < len(tag) && tag[i+1] == ',':
+			current.WriteByte(',')
+			i++
+		case tag[i] == ',':
+			flush()
+		default:
+			current.WriteByte(tag[i])
+		}
+	}
+	flush()
+
+	return rules
+}
+
+// GetValidator finds a validator by name.
+// Validators registered in m.Validators take precedence over the standard validators.
+//
+// The standard validators are:
+//
+//   - "min" and "max": the numeric value must be at least or at most the argument
+//   - "minlen" and "maxlen": the length of a string, slice, array or map must be at least or at most the argument
+//   - "regex": the string value must match the regular expression in the argument
+//   - "oneof": the formatted value must be one of the space-separated words in the argument
+//
+// The standard validators dereference pointers; nil pointers are always valid.
+func (m Marshal) GetValidator(name string) (Validator, error) {
+	if validator, ok := m.Validators[name]; ok && validator != nil {
+		return validator, nil
+	}
+	if validator, ok := standardValidators[name]; ok {
+		return validator, nil
+	}
+	return nil, ErrUnknownValidator
+}
+
+// RegisterValidator registers a new Validator with m.
+//
+// Validator should not be nil.
+// No checking of this condition is performed; it should be ensured by the caller.
+func (m *Marshal) RegisterValidator(name string, validator Validator) {
+	if m.Validators == nil {
+		m.Validators = make(map[string]Validator)
+	}
+	m.Validators[name] = validator
+}
+
+// standardValidators holds the validators that are always available, see GetValidator.
+var standardValidators = map[string]Validator{
+	"min":    validateBound(false),
+	"max":    validateBound(true),
+	"minlen": validateLength(false),
+	"maxlen": validateLength(true),
+	"regex":  validateRegex,
+	"oneof":  validateOneOf,
+}
+
+// validateIndirect dereferences value.
+// When value is a nil pointer, returns an invalid reflect.Value.
+func validateIndirect(value interface{}) reflect.Value {
+	rValue := reflect.ValueOf(value)
+	for rValue.Kind() == reflect.Ptr {
+		if rValue.IsNil() {
+			return reflect.Value{}
+		}
+		rValue = rValue.Elem()
+	}
+	return rValue
+}
+
+// validateBound returns a validator comparing a numeric value against the argument.
+func validateBound(isMax bool) Validator {
+	return func(value interface{}, arg string, ctx UnmarshalContext) error {
+		rValue := validateIndirect(value)
+		if !rValue.IsValid() {
+			return nil
+		}
+
+		var cmp int
+		switch rValue.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			bound, err := strconv.ParseInt(arg, 10, 64)
+			if err != nil {
+				return err
+			}
+			cmp = compareInt64(rValue.Int(), bound)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			bound, err := strconv.ParseUint(arg, 10, 64)
+			if err != nil {
+				return err
+			}
+			cmp = compareUint64(rValue.Uint(), bound)
+		case reflect.Float32, reflect.Float64:
+			bound, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				return err
+			}
+			cmp = compareFloat64(rValue.Float(), bound)
+		default:
+			return fmt.Errorf("cannot compare value of type %s", rValue.Type())
+		}
+
+		switch {
+		case isMax && cmp > 0:
+			return fmt.Errorf("value %v is greater than maximum %s", rValue, arg)
+		case !isMax && cmp < 0:
+			return fmt.Errorf("value %v is less than minimum %s", rValue, arg)
+		}
+		return nil
+	}
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareUint64(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareFloat64(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// validateLength returns a validator comparing the length of a value against the argument.
+func validateLength(isMax bool) Validator {
+	return func(value interface{}, arg string, ctx UnmarshalContext) error {
+		rValue := validateIndirect(value)
+		if !rValue.IsValid() {
+			return nil
+		}
+
+		bound, err := strconv.Atoi(arg)
+		if err != nil {
+			return err
+		}
+
+		var length int
+		switch rValue.Kind() {
+		case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+			length = rValue.Len()
+		default:
+			return fmt.Errorf("value of type %s has no length", rValue.Type())
+		}
+
+		switch {
+		case isMax && length > bound:
+			return fmt.Errorf("length %d is greater than maximum %d", length, bound)
+		case !isMax && length < bound:
+			return fmt.Errorf("length %d is less than minimum %d", length, bound)
+		}
+		return nil
+	}
+}
+
+// regexCache caches compiled regular expressions used by validateRegex.
+var regexCache sync.Map
+
+func validateRegex(value interface{}, arg string, ctx UnmarshalContext) error {
+	rValue := validateIndirect(value)
+	if !rValue.IsValid() {
+		return nil
+	}
+	if rValue.Kind() != reflect.String {
+		return fmt.Errorf("cannot match value of type %s", rValue.Type())
+	}
+
+	var expr *regexp.Regexp
+	if cached, ok := regexCache.Load(arg); ok {
+		expr = cached.(*regexp.Regexp)
+	} else {
+		var err error
+		expr, err = regexp.Compile(arg)
+		if err != nil {
+			return err
+		}
+		regexCache.Store(arg, expr)
+	}
+
+	if !expr.MatchString(rValue.String()) {
+		return fmt.Errorf("value %q does not match %q", rValue.String(), arg)
+	}
+	return nil
+}
+
+func validateOneOf(value interface{}, arg string, ctx UnmarshalContext) error {
+	rValue := validateIndirect(value)
+	if !rValue.IsValid() {
+		return nil
+	}
+
+	formatted := fmt.Sprint(rValue.Interface())
+	for _, option := range strings.Fields(arg) {
+		if option == formatted {
+			return nil
+		}
+	}
+	return fmt.Errorf("value %q is not one of %q", formatted, arg)
+}
@@ -0,0 +1,155 @@
+package stringreader
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_parseValidateTag(t *testing.T) {
+	tests := []struct {
+		name      string
+		tag       string
+		wantRules []validationRule
+	}{
+		{"empty", "", nil},
+		{"single rule", "min=1", []validationRule{{"min", "1"}}},
+		{"rule without argument", "nonzero", []validationRule{{"nonzero", ""}}},
+		{"multiple rules", "min=1,max=10", []validationRule{{"min", "1"}, {"max", "10"}}},
+		{"escaped comma", `regex=^a{1\,3}$,maxlen=3`, []validationRule{{"regex", "^a{1,3}$"}, {"maxlen", "3"}}},
+		{"equals in argument", "regex=a=b", []validationRule{{"regex", "a=b"}}},
+		{"empty rules", ",min=1,,", []validationRule{{"min", "1"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseValidateTag(tt.tag); !reflect.DeepEqual(got, tt.wantRules) {
+				t.Errorf("parseValidateTag() = %v, want = %v", got, tt.wantRules)
+			}
+		})
+	}
+}
+
+func Test_standardValidators(t *testing.T) {
+	two := 2
+
+	tests := []struct {
+		name    string
+		rule    string
+		value   interface{}
+		arg     string
+		wantErr bool
+	}{
+		{"min int ok", "min", 5, "1", false},
+		{"min int fail", "min", 0, "1", true},
+		{"max uint ok", "max", uint8(10), "10", false},
+		{"max uint fail", "max", uint8(11), "10", true},
+		{"min float fail", "min", 0.5, "1", true},
+		{"min pointer", "min", &two, "3", true},
+		{"min nil pointer", "min", (*int)(nil), "3", false},
+		{"min not a number", "min", "hello", "3", true},
+		{"min bad argument", "min", 1, "one", true},
+		{"minlen string fail", "minlen", "ab", "3", true},
+		{"maxlen slice ok", "maxlen", []string{"a", "b"}, "2", false},
+		{"maxlen map fail", "maxlen", map[string]int{"a": 1, "b": 2}, "1", true},
+		{"maxlen no length", "maxlen", 42, "1", true},
+		{"regex ok", "regex", "abc123", "^[a-z]+[0-9]+$", false},
+		{"regex fail", "regex", "123abc", "^[a-z]+[0-9]+$", true},
+		{"regex invalid", "regex", "abc", "(", true},
+		{"oneof ok", "oneof", "debug", "debug info warn", false},
+		{"oneof int ok", "oneof", 2, "1 2 3", false},
+		{"oneof fail", "oneof", "trace", "debug info warn", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := standardValidators[tt.rule]
+			if err := validator(tt.value, tt.arg, nil); (err != nil) != tt.wantErr {
+				t.Errorf("%s(%v, %q) error = %v, wantErr = %t", tt.rule, tt.value, tt.arg, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMarshal_Unmarshal_validate(t *testing.T) {
+	var m Marshal
+	m.ParserTag = "type"
+	m.ValidateTag = "validate"
+	m.CollectErrors = true
+	m.RegisterStandardParsers()
+	m.RegisterValidator("even", func(value interface{}, arg string, ctx UnmarshalContext) error {
+		if value.(int)%2 != 0 {
+			return errors.New("not even")
+		}
+		return nil
+	})
+
+	var dest struct {
+		Port  int      `validate:"min=1,max=65535"`
+		Level string   `validate:"oneof=debug info warn"`
+		Tags  []string `validate:"minlen=1"`
+		Count int      `validate:"even"`
+		Fake  string   `validate:"fake"`
+		Valid string   `validate:"regex=^[a-z]+$"`
+	}
+
+	err := m.Unmarshal(&dest, SourceSmartSplit{
+		SourceSingle: SourceSingleMap{
+			"Port":  "70000",
+			"Level": "trace",
+			"Count": "3",
+			"Valid": "abc",
+		},
+		SourceMulti: SourceMultiMap{},
+	})
+
+	var collected ErrCollected
+	if !errors.As(err, &collected) {
+		t.Fatalf("Marshal.Unmarshal() err = %v, want ErrCollected", err)
+	}
+
+	var got []string
+	for _, e := range collected.Errors {
+		vErr, ok := e.(ErrValidationFailed)
+		if !ok {
+			t.Errorf("Marshal.Unmarshal() returned unexpected error %v", e)
+			continue
+		}
+		got = append(got, vErr.Dest()+":"+vErr.Rule)
+	}
+	want := []string{"Port:max", "Level:oneof", "Tags:minlen", "Count:even", "Fake:fake"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Marshal.Unmarshal() failed rules = %v, want = %v", got, want)
+	}
+
+	if !errors.Is(collected.Errors[len(collected.Errors)-1], ErrUnknownValidator) {
+		t.Errorf("Marshal.Unmarshal() unknown rule err = %v, want ErrUnknownValidator", collected.Errors[len(collected.Errors)-1])
+	}
+
+	// the value is assigned even when validation fails
+	if dest.Port != 70000 || dest.Valid != "abc" {
+		t.Errorf("Marshal.Unmarshal() dest = %+v, want assigned values", dest)
+	}
+}
+
+func ExampleErrValidationFailed() {
+	var m Marshal
+	m.ParserTag = "type"
+	m.ValidateTag = "validate"
+	m.RegisterStandardParsers()
+
+	var dest struct {
+		Port int `validate:"min=1,max=65535"`
+	}
+
+	source, err := ParseDotEnv("config.env", strings.NewReader("Port=70000\n"))
+	if err != nil {
+		panic(err)
+	}
+
+	err = m.Unmarshal(&dest, source)
+	fmt.Println(err)
+
+	// Output:
+	// Marshal.Unmarshal: Field "Port" failed validation max (at config.env:1): value 70000 is greater than maximum 65535
+}